@@ -1,74 +1,111 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"runtime"
+	"time"
 
 	"github.com/vthommeret/glossterm/lib/gt"
 )
 
 const defaultInput = "data/words.gob"
-const defaultCognatesInput = "data/cognates.jsonl"
-const defaultOutput = "data/words.gob"
+const defaultMinScore = 0
+const defaultWeakScore = 0.5
 
 var input string
-var cognatesInput string
+var graphInput string
 var output string
+var workers int
+var minScore float64
+var weakScore float64
 
 func init() {
-	flag.StringVar(&input, "i", defaultInput, "Input file (gob format)")
-	flag.StringVar(&cognatesInput, "ci", defaultCognatesInput, "Cognates input file (json line format)")
-	flag.StringVar(&output, "o", defaultOutput, "Output file (gob format)")
+	flag.StringVar(&input, "i", defaultInput, "Input file (gob format, or word store ending in .db which is updated in place)")
+	flag.StringVar(&graphInput, "gi", "", "Graph file in nquads format (built from the input words by default)")
+	flag.StringVar(&output, "o", "", "Output file for gobs (the input file by default)")
+	flag.IntVar(&workers, "workers", runtime.NumCPU(), "Number of words to find cognates for at once")
+	flag.Float64Var(&minScore, "min-score", defaultMinScore, "Minimum score of cognates (0 to 1)")
+	flag.Float64Var(&weakScore, "weak-score", defaultWeakScore, "Score below which cognates are marked as weak (0 to 1)")
 	flag.Parse()
 }
 
 func main() {
-	// Read all cognates
+	start := time.Now()
 
-	f, err := os.Open(cognatesInput)
-	if err != nil {
-		log.Fatalf("Unable to open cognates input file: %s, %s", cognatesInput, err)
-	}
-	defer f.Close()
-
-	cognateWords := map[string]*gt.Word{}
-	count := 0
+	// Stop on interrupt, keeping the words already written to a store.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		var word *gt.Word
-		if err = json.Unmarshal(scanner.Bytes(), &word); err != nil {
-			log.Fatalf("Unable to read JSON: %s", err)
+	// Get words and graph. Graphs of stores are read before the store is
+	// opened for writing.
+	var graph *gt.Graph
+	var err error
+	if graphInput != "" {
+		graph, err = gt.LoadGraph(graphInput)
+		if err != nil {
+			log.Fatalf("Unable to get %q graph: %s", graphInput, err)
 		}
-		cognateWords[word.Name] = word
-		count++
 	}
-
-	// Resolve cognates
-
-	wordMap, err := gt.GetWords(input)
-	if err != nil {
-		log.Fatalf("Unable to get %q words: %s", input, err)
-	}
-
-	for name, word := range wordMap {
-		if cognateWord, ok := cognateWords[name]; ok {
-			for code, lang := range cognateWord.Languages {
-				if _, ok = word.Languages[code]; ok {
-					wordMap[name].Languages[code].Cognates = lang.Cognates
-				}
+	var words gt.Words
+	var wordMap map[string]*gt.Word
+	if gt.IsStore(input) {
+		if graph == nil {
+			graph, err = gt.ReadGraph(input)
+			if err != nil {
+				log.Fatalf("Unable to get %q graph: %s", input, err)
 			}
 		}
+		ws, err := gt.OpenWordStore(input)
+		if err != nil {
+			log.Fatalf("Unable to open %q: %s", input, err)
+		}
+		defer ws.Close()
+		words = ws
+	} else {
+		wordMap, err = gt.GetWords(input)
+		if err != nil {
+			log.Fatalf("Unable to get %q words: %s", input, err)
+		}
+		words = gt.NewWordMap(wordMap)
+		if graph == nil {
+			graph = gt.BuildGraph(wordMap)
+		}
 	}
+	fmt.Fprintf(os.Stderr, "Got graph of %d quads in %s.\n", graph.EdgeCount(), time.Since(start))
 
-	fmt.Printf("Writing cognates for %d words.\n", count)
-
-	err = gt.WriteGob(output, wordMap, true, false)
+	stats, err := gt.ComputeCognates(ctx, graph, words, gt.CognateOptions{
+		Workers:   workers,
+		MinScore:  minScore,
+		WeakScore: weakScore,
+		Progress: func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rFound cognates for %d/%d words", done, total)
+		},
+	})
+	fmt.Fprintln(os.Stderr)
 	if err != nil {
-		log.Fatalf("Unable to write and compress %s: %s", output, err)
+		log.Fatalf("Unable to find cognates: %s", err)
+	}
+
+	fmt.Printf("Found %d cognates for %d of %d words, %d changed, in %s.\n", stats.Cognates, stats.WithCognates, stats.Words, stats.Changed, time.Since(start))
+
+	if wordMap != nil {
+		if output == "" {
+			output = input
+		}
+		err = gt.WriteGob(output, wordMap, true, false)
+		if err != nil {
+			log.Fatalf("Unable to write and compress %s: %s", output, err)
+		}
 	}
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/vthommeret/glossterm/lib/gt"
+)
+
+const defaultInput = "data/words.gob"
+const defaultOutput = "data/words.db"
+
+var input string
+var output string
+
+func init() {
+	flag.StringVar(&input, "i", defaultInput, "Input file (gob or .gob.gz format)")
+	flag.StringVar(&output, "o", defaultOutput, "Output word store")
+	flag.Parse()
+}
+
+func main() {
+	start := time.Now()
+
+	if !gt.IsStore(output) {
+		log.Fatalf("Output %q must end in %s.", output, gt.StoreExt)
+	}
+
+	// Start from an empty store so removed words don't linger.
+	if err := os.Remove(output); err != nil && !os.IsNotExist(err) {
+		log.Fatalf("Unable to remove %q: %s", output, err)
+	}
+
+	n, err := gt.ConvertWords(input, output)
+	if err != nil {
+		log.Fatalf("Unable to convert %q to %q: %s", input, output, err)
+	}
+
+	fmt.Printf("Wrote %d words to %q in %s\n", n, output, time.Since(start))
+}
@@ -1,35 +1,41 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
 	"strings"
 
-	"github.com/cayleygraph/cayley"
-	"github.com/cayleygraph/quad/nquads"
 	"github.com/vthommeret/glossterm/lib/gt"
 )
 
 const defaultInput = "data/words.nq"
+const defaultFormat = "text"
+const defaultDepth = 3
 
 var input string
+var format string
+var output string
+var depth int
 
 func init() {
-	flag.StringVar(&input, "i", defaultInput, "Input file (nquads format)")
+	flag.StringVar(&input, "i", defaultInput, "Input file (nquads format, or words in gob format or a word store ending in .db)")
+	flag.StringVar(&format, "f", defaultFormat, "Output format (text, dot or svg)")
+	flag.StringVar(&output, "o", "", "Output file (stdout by default)")
+	flag.IntVar(&depth, "depth", defaultDepth, "Max number of ancestors and descendants (-1 for no limit)")
 	flag.Parse()
 }
 
 func main() {
-	if len(os.Args) < 2 {
+	if flag.NArg() < 1 {
 		log.Fatalf("Must specify word, e.g. es/helado.")
 	}
-	w := os.Args[1]
+	w := flag.Arg(0)
 
-	parts := strings.Split(w, "/")
+	parts := strings.SplitN(w, "/", 2)
 	if len(parts) < 2 {
 		log.Fatalf("Word must be in <lang>/<word> format, e.g. es/helado")
 	}
@@ -37,37 +43,64 @@ func main() {
 	lang := parts[0]
 	word := parts[1]
 
-	fmt.Printf("Word: %s (%s)\n", word, lang)
-
-	f, err := os.Open(input)
+	graph, err := gt.LoadGraph(input)
 	if err != nil {
-		log.Fatalf("Unable to open %s input: %s", input, err)
+		log.Fatalf("Unable to get %q graph: %s", input, err)
 	}
 
-	r := bufio.NewReader(f)
-	nr := nquads.NewReader(r, false)
+	var out io.Writer = os.Stdout
+	if output != "" {
+		of, err := os.Create(output)
+		if err != nil {
+			log.Fatalf("Unable to create %q: %s", output, err)
+		}
+		defer of.Close()
+		out = of
+	}
 
-	store, err := cayley.NewMemoryGraph()
+	tree := gt.GetEtymologyTree(graph, lang, word, depth)
+
+	switch format {
+	case "text":
+		fmt.Fprintf(out, "Word: %s (%s)\n", word, lang)
+		err = tree.WriteText(out)
+		if err == nil {
+			err = writeCognates(out, gt.GetCognates(graph, lang, word))
+		}
+	case "dot":
+		err = tree.WriteDOT(out)
+	case "svg":
+		err = tree.WriteSVG(out)
+	default:
+		log.Fatalf("Unknown format %q, must be text, dot or svg.", format)
+	}
 	if err != nil {
-		log.Fatalf("Unable to create memory store: %s", err)
+		log.Fatalf("Unable to write %s: %s", format, err)
 	}
+}
 
-	for {
-		q, err := nr.ReadQuad()
-		if err != nil {
-			if err != io.EOF {
-				log.Fatalf("Unable to read quad: %s", err)
+func writeCognates(w io.Writer, cognates map[string]*gt.Cognate) error {
+	var names []string
+	for name := range cognates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if _, err := fmt.Fprintln(w, "Cognates:"); err != nil {
+		return err
+	}
+	for _, name := range names {
+		c := cognates[name]
+		path := []string{}
+		for i, e := range c.Path {
+			if i == 0 {
+				path = append(path, e.From)
 			}
-			break
+			path = append(path, e.Predicate, e.To)
 		}
-		if !q.IsValid() {
-			fmt.Printf("Got invalid quad: %+v\n", q)
+		if _, err := fmt.Fprintf(w, "%s (%s, %.2f): %s\n", c.Word, c.From, c.Score, strings.Join(path, " ")); err != nil {
+			return err
 		}
-		store.AddQuad(q)
-	}
-
-	ds := gt.GetCognates(store, lang, word)
-	for _, d := range ds {
-		fmt.Printf("%s (%s)\n", d.Word, d.From)
 	}
+	return nil
 }
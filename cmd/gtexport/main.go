@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/vthommeret/glossterm/lib/gt"
+)
+
+const defaultInput = "data/words.gob"
+const defaultFormat = "jsonl"
+
+var outputs = map[string]string{
+	"jsonl": "data/words.jsonl",
+	"proto": "data/words.pb",
+}
+
+var input string
+var output string
+var format string
+
+func init() {
+	flag.StringVar(&input, "i", defaultInput, "Input file (gob format, decoded in full, or a word store ending in .db, read one word at a time)")
+	flag.StringVar(&output, "o", "", "Output file (data/words.jsonl or data/words.pb by default)")
+	flag.StringVar(&format, "f", defaultFormat, "Output format (jsonl or proto)")
+	flag.Parse()
+}
+
+func main() {
+	start := time.Now()
+
+	var write func(io.Writer, *gt.Word) error
+	switch format {
+	case "jsonl":
+		write = gt.WriteJSONL
+	case "proto":
+		write = gt.WriteProto
+	default:
+		log.Fatalf("Unknown format %q, must be jsonl or proto.", format)
+	}
+	if output == "" {
+		output = outputs[format]
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		log.Fatalf("Unable to create %q: %s", output, err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	count := 0
+	err = gt.ForEachWord(input, func(word *gt.Word) error {
+		count++
+		return write(w, word)
+	})
+	if err != nil {
+		log.Fatalf("Unable to export %q: %s", input, err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Unable to write %q: %s", output, err)
+	}
+
+	fmt.Printf("Wrote %d words to %q in %s\n", count, output, time.Since(start))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/vthommeret/glossterm/lib/gt"
+)
+
+const defaultInput = "data/words.nq"
+const defaultFormat = "graphml"
+const defaultDepth = 2
+
+var outputs = map[string]string{
+	"graphml": "data/words.graphml",
+	"dot":     "data/words.dot",
+	"json":    "data/words.json",
+}
+
+var exportFlags = flag.NewFlagSet("export", flag.ExitOnError)
+
+var input string
+var output string
+var format string
+var word string
+var depth int
+
+func init() {
+	exportFlags.StringVar(&input, "i", defaultInput, "Input file (nquads format from gtquads, optionally .gz)")
+	exportFlags.StringVar(&output, "o", "", "Output file, or - for stdout (data/words.<format> by default)")
+	exportFlags.StringVar(&format, "f", defaultFormat, "Output format (graphml, dot or json)")
+	exportFlags.StringVar(&word, "word", "", "Only export the neighbourhood of a word, e.g. es/hermano")
+	exportFlags.IntVar(&depth, "depth", defaultDepth, "Max number of edges from -word (-1 for no limit)")
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "export" {
+		log.Fatalf("Usage: gtgraph export [flags]")
+	}
+	exportFlags.Parse(os.Args[2:])
+
+	var write func(*gt.QuadGraph, io.Writer) error
+	switch format {
+	case "graphml":
+		write = (*gt.QuadGraph).WriteGraphML
+	case "dot":
+		write = (*gt.QuadGraph).WriteDOT
+	case "json":
+		write = (*gt.QuadGraph).WriteNodeLink
+	default:
+		log.Fatalf("Unknown format %q, must be graphml, dot or json.", format)
+	}
+	if output == "" {
+		output = outputs[format]
+	}
+
+	f, err := os.Open(input)
+	if err != nil {
+		log.Fatalf("Unable to open %q: %s", input, err)
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(input, ".gz") {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			log.Fatalf("Unable to uncompress %q: %s", input, err)
+		}
+		r = gr
+	}
+
+	g, err := gt.ReadQuadGraph(r)
+	if err != nil {
+		log.Fatalf("Unable to read %q: %s", input, err)
+	}
+	if word != "" {
+		g = g.Neighbourhood(word, depth)
+		if len(g.Nodes) == 0 {
+			log.Fatalf("Unable to find word: %s", word)
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if output != "-" {
+		of, err := os.Create(output)
+		if err != nil {
+			log.Fatalf("Unable to create %q: %s", output, err)
+		}
+		defer of.Close()
+		out = of
+	}
+	w := bufio.NewWriter(out)
+	if err := write(g, w); err != nil {
+		log.Fatalf("Unable to write %q: %s", output, err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Unable to write %q: %s", output, err)
+	}
+
+	if output != "-" {
+		fmt.Printf("Wrote %d nodes and %d edges to %q\n", len(g.Nodes), len(g.Edges), output)
+	}
+}
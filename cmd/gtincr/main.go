@@ -0,0 +1,140 @@
+package main
+
+import (
+	"compress/bzip2"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vthommeret/glossterm/lib/gt"
+)
+
+const defaultWordsFile = "data/words.gob"
+const defaultDescendantsFile = "data/descendants.gob"
+const defaultNoBackup = false
+const defaultDepth = gt.DefaultDescTreeDepth
+
+var date string
+var inputFile string
+var wordsFile string
+var descendantsFile string
+var noBackup bool
+var depth int
+
+func init() {
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("20060102")
+	flag.StringVar(&date, "date", yesterday, "Date of adds-changes dump (YYYYMMDD)")
+	flag.StringVar(&inputFile, "i", "", "Input file or URL (xml or .xml.bz2). Defaults to the adds-changes dump for -date.")
+	flag.StringVar(&wordsFile, "w", defaultWordsFile, "Words file to patch (gob format)")
+	flag.StringVar(&descendantsFile, "d", defaultDescendantsFile, "Descendants file to patch (gob format)")
+	flag.BoolVar(&noBackup, "no-backup", defaultNoBackup, "Whether to not backup words. gtindex compares to the backup.")
+	flag.IntVar(&depth, "depth", defaultDepth, "Max number of levels of desctrees to resolve in changed words (-1 for no limit)")
+	flag.Parse()
+}
+
+func main() {
+	start := time.Now()
+
+	input := inputFile
+	if input == "" {
+		d, err := time.Parse("20060102", date)
+		if err != nil {
+			log.Fatalf("Unable to parse date %q: %s", date, err)
+		}
+		status, err := fetchString(gt.IncrementalStatusURL(d))
+		if err != nil {
+			log.Fatalf("Unable to get dump status for %s: %s", date, err)
+		}
+		if strings.TrimSpace(status) != "done" {
+			log.Fatalf("Dump for %s isn't done yet (status %q).", date, strings.TrimSpace(status))
+		}
+		input = gt.IncrementalDumpURL(d)
+	}
+
+	r, err := open(input)
+	if err != nil {
+		log.Fatalf("Unable to open %s: %s", input, err)
+	}
+	defer r.Close()
+
+	var dr io.Reader = r
+	if strings.HasSuffix(input, ".bz2") {
+		dr = bzip2.NewReader(r)
+	}
+
+	fmt.Printf("Parsing changes from %s\n", input)
+
+	errorsCount := 0
+	changes, err := gt.ParseChanges(dr, gt.ParseOptions{}, func(e gt.Error) {
+		fmt.Fprintf(os.Stderr, "Error parsing words: %s\n", e)
+		errorsCount++
+	})
+	if err != nil {
+		log.Fatalf("Unable to parse changes: %s", err)
+	}
+
+	fmt.Printf("%d changed words, %d changed descendant trees, %d errors\n", len(changes.Words), len(changes.Descendants), errorsCount)
+
+	words, err := gt.GetWords(wordsFile)
+	if err != nil {
+		log.Fatalf("Unable to get %q words: %s", wordsFile, err)
+	}
+
+	var descendants map[string]gt.Descendants
+	err = gt.ReadGob(descendantsFile, &descendants)
+	if err != nil {
+		log.Fatalf("Unable to get %q descendants: %s", descendantsFile, err)
+	}
+
+	ws, ds := changes.Apply(words, descendants, depth)
+
+	fmt.Printf("Words: %d added, %d updated, %d removed, %d unchanged\n", ws.Added, ws.Updated, ws.Removed, ws.Unchanged)
+	fmt.Printf("Descendant trees: %d added, %d updated, %d removed, %d unchanged\n", ds.Added, ds.Updated, ds.Removed, ds.Unchanged)
+
+	err = gt.WriteGob(wordsFile, words, true, !noBackup)
+	if err != nil {
+		log.Fatalf("Unable to write and compress %s: %s", wordsFile, err)
+	}
+
+	err = gt.WriteGob(descendantsFile, descendants, true, true)
+	if err != nil {
+		log.Fatalf("Unable to write and compress %s: %s", descendantsFile, err)
+	}
+
+	fmt.Printf("Patched in %s\n", time.Since(start))
+}
+
+// open opens a local file or URL.
+func open(input string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(input, "http://") && !strings.HasPrefix(input, "https://") {
+		return os.Open(input)
+	}
+	res, err := http.Get(input)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return res.Body, nil
+}
+
+func fetchString(url string) (string, error) {
+	r, err := open(url)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
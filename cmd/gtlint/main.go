@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/vthommeret/glossterm/lib/gt"
+)
+
+const defaultInput = "data/words.gob"
+const defaultGraphOutput = "data/lint-graph.json"
+const defaultRefsOutput = "data/lint-refs.json"
+const defaultSuggest = false
+
+var graphFlags = flag.NewFlagSet("graph", flag.ExitOnError)
+var refsFlags = flag.NewFlagSet("refs", flag.ExitOnError)
+
+var input string
+var output string
+var suggest bool
+
+func init() {
+	for _, fs := range []*flag.FlagSet{graphFlags, refsFlags} {
+		fs.StringVar(&input, "i", defaultInput, "Input file (gob format, or word store ending in .db)")
+	}
+	graphFlags.StringVar(&output, "o", defaultGraphOutput, "Output file (JSON format), or - for stdout")
+	refsFlags.StringVar(&output, "o", defaultRefsOutput, "Output file (JSON format), or - for stdout")
+	refsFlags.BoolVar(&suggest, "suggest", defaultSuggest, "Suggest entries whose names only differ by diacritics or case")
+}
+
+type report interface {
+	WriteJSON(w io.Writer) error
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalf("Usage: gtlint graph|refs [flags]")
+	}
+
+	var r report
+	var summary string
+	switch os.Args[1] {
+	case "graph":
+		graphFlags.Parse(os.Args[2:])
+		g, err := gt.ReadEtymologyGraph(input)
+		if err != nil {
+			log.Fatalf("Unable to get %q graph: %s", input, err)
+		}
+		gr := gt.LintGraph(g)
+		r = gr
+		summary = fmt.Sprintf("Checked %d words and %d edges: %d cycles, %d self-loops, %d unreciprocated descendants, %d language order errors.",
+			gr.Words, gr.Edges, len(gr.Cycles), len(gr.SelfLoops), len(gr.UnreciprocatedDescendants), len(gr.LanguageOrder))
+	case "refs":
+		refsFlags.Parse(os.Args[2:])
+		rr, err := gt.ReadDanglingRefs(input, gt.RefOptions{Suggest: suggest})
+		if err != nil {
+			log.Fatalf("Unable to find %q references: %s", input, err)
+		}
+		r = rr
+		summary = fmt.Sprintf("Checked %d references: %d to words without an entry in %d languages.", rr.Refs, rr.Dangling, len(rr.Languages))
+	default:
+		log.Fatalf("Unknown check %q, must be graph or refs.", os.Args[1])
+	}
+
+	var out io.Writer = os.Stdout
+	if output != "-" {
+		of, err := os.Create(output)
+		if err != nil {
+			log.Fatalf("Unable to create %q: %s", output, err)
+		}
+		defer of.Close()
+		out = of
+	}
+	w := bufio.NewWriter(out)
+	if err := r.WriteJSON(w); err != nil {
+		log.Fatalf("Unable to write %q: %s", output, err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Unable to write %q: %s", output, err)
+	}
+
+	if output != "-" {
+		fmt.Printf("%s Wrote %q\n", summary, output)
+	}
+}
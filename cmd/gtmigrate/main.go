@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vthommeret/glossterm/lib/gt"
+)
+
+// Migrated when no files are given. Missing files are skipped.
+var defaultFiles = []string{
+	"data/words.gob",
+	"data/descendants.gob",
+	"data/previous/words.gob",
+	"data/previous/descendants.gob",
+}
+
+var kind string
+var list bool
+
+func init() {
+	flag.StringVar(&kind, "kind", "", "Kind of unversioned gobs (words or descendants). Defaults to descendants if the file name contains \"descendants\", otherwise words.")
+	flag.BoolVar(&list, "list", false, "List migrations instead of migrating")
+	flag.Parse()
+}
+
+func main() {
+	if list {
+		for _, m := range gt.Migrations() {
+			fmt.Printf("%d: %s\n", m.Version, m.Description)
+		}
+		return
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		for _, f := range defaultFiles {
+			if exists(f) || exists(f+".gz") {
+				files = append(files, f)
+			}
+		}
+	}
+
+	for _, f := range files {
+		k := gt.GobKind(kind)
+		if k == "" {
+			k = gt.WordsGob
+			if strings.Contains(filepath.Base(f), "descendants") {
+				k = gt.DescendantsGob
+			}
+		}
+		from, err := gt.MigrateGob(f, k)
+		if err != nil {
+			log.Fatalf("Unable to migrate %q: %s", f, err)
+		}
+		if from == gt.GobVersion {
+			fmt.Printf("%q is already at version %d\n", f, gt.GobVersion)
+		} else {
+			fmt.Printf("Migrated %q from version %d to %d\n", f, from, gt.GobVersion)
+		}
+	}
+}
+
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return !os.IsNotExist(err)
+}
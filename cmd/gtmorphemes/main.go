@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/vthommeret/glossterm/lib/gt"
+)
+
+const defaultInput = "data/words.gob"
+
+var input string
+
+func init() {
+	flag.StringVar(&input, "i", defaultInput, "Input file (gob format, or word store ending in .db)")
+	flag.Parse()
+}
+
+func main() {
+	if flag.NArg() < 1 {
+		log.Fatalf("Must specify morpheme, e.g. es/-ción.")
+	}
+	parts := strings.SplitN(flag.Arg(0), "/", 2)
+	if len(parts) < 2 {
+		log.Fatalf("Morpheme must be in <lang>/<form> format, e.g. es/-ción")
+	}
+
+	idx, err := gt.ReadMorphemeIndex(input)
+	if err != nil {
+		log.Fatalf("Unable to index %q morphemes: %s", input, err)
+	}
+
+	words := idx.Words(parts[0], parts[1])
+	for i, w := range words {
+		fmt.Printf("%2d. %s\n", i+1, w)
+	}
+	if len(words) == 0 {
+		fmt.Printf("No words found.\n")
+	}
+}
@@ -1,12 +1,12 @@
 package main
 
 import (
-	"encoding/xml"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/vthommeret/glossterm/lib/gt"
 )
@@ -14,9 +14,11 @@ import (
 const defaultInput = "cmd/gtsplit/pages.xml"
 
 var inputFile string
+var outputDir string
 
 func init() {
 	flag.StringVar(&inputFile, "i", defaultInput, "Input file (xml format)")
+	flag.StringVar(&outputDir, "o", "", "Output directory, with a <title>.xml file per page (default stdout)")
 	flag.Parse()
 }
 
@@ -25,7 +27,13 @@ func main() {
 	if len(args) == 0 {
 		log.Fatalf("Must specify page title.")
 	}
-	t := args[0]
+	if len(args) > 1 && outputDir == "" {
+		log.Fatalf("Must specify an output directory for more than one page title.")
+	}
+	titles := map[string]bool{}
+	for _, t := range args {
+		titles[t] = true
+	}
 
 	files, err := gt.GetSplitFiles(inputFile)
 	if err != nil {
@@ -44,10 +52,10 @@ func main() {
 	completed := 0
 
 	for _, f := range files {
-		go gt.ParseXMLPage(f, t, pageCh, errorsCh, doneCh)
+		go gt.ParseXMLPage(f, titles, pageCh, errorsCh, doneCh)
 	}
 
-	var page *gt.Page
+	pages := map[string]gt.Page{}
 
 Loop:
 	for {
@@ -61,20 +69,49 @@ Loop:
 				break Loop
 			}
 		case p := <-pageCh:
-			page = &p
-			break Loop
+			pages[p.Title] = p
+			if len(pages) == len(titles) {
+				break Loop
+			}
 		}
 	}
 
-	if page == nil {
-		fmt.Println("Unable to find word.")
-		os.Exit(1)
+	if outputDir == "" {
+		page, ok := pages[args[0]]
+		if !ok {
+			fmt.Println("Unable to find word.")
+			os.Exit(1)
+		}
+		err = gt.WritePageXML(os.Stdout, page)
+		if err != nil {
+			log.Fatalf("Unable to XML encode word: %s", err)
+		}
+		return
 	}
 
-	e := xml.NewEncoder(os.Stdout)
-	e.Indent("", "  ")
-	err = e.Encode(page)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		log.Fatalf("Unable to create %s: %s", outputDir, err)
+	}
+	for _, t := range args {
+		page, ok := pages[t]
+		if !ok {
+			log.Printf("Unable to find %q.", t)
+			continue
+		}
+		if err := writePage(filepath.Join(outputDir, gt.PageFilename(t)+".xml"), page); err != nil {
+			log.Fatalf("Unable to write %q: %s", t, err)
+		}
+	}
+}
+
+func writePage(path string, p gt.Page) error {
+	f, err := os.Create(path)
 	if err != nil {
-		log.Fatalf("Unable to XML encode word: %s", err)
+		return err
+	}
+	if err := gt.WritePageXML(f, p); err != nil {
+		f.Close()
+		return err
 	}
+	return f.Close()
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
@@ -13,6 +14,10 @@ import (
 const defaultInputFile = "cmd/gtsplit/pages.xml"
 const defaultOutputFile = "data/words.gob"
 const defaultDescendantsOutputFile = "data/descendants.gob"
+const defaultDiagnosticsOutputFile = "data/diagnostics.json"
+const defaultErrorsOutputFile = ""
+const defaultQuarantineDir = "data/quarantine"
+const defaultMaxErrors = -1
 const defaultNoBackup = false
 
 const total = 3150000 // approximate
@@ -21,12 +26,20 @@ const step = total / 100
 var inputFile string
 var outputFile string
 var descendantsOutputFile string
+var diagnosticsOutputFile string
+var errorsOutputFile string
+var quarantineDir string
+var maxErrors int
 var noBackup bool
 
 func init() {
 	flag.StringVar(&inputFile, "i", defaultInputFile, "Input file (xml format)")
 	flag.StringVar(&outputFile, "o", defaultOutputFile, "Output file (gob format)")
 	flag.StringVar(&descendantsOutputFile, "do", defaultDescendantsOutputFile, "Descendants output file (gob format)")
+	flag.StringVar(&diagnosticsOutputFile, "diag", defaultDiagnosticsOutputFile, "Diagnostics output file (json format)")
+	flag.StringVar(&errorsOutputFile, "errors", defaultErrorsOutputFile, "Errors output file (json lines format)")
+	flag.StringVar(&quarantineDir, "quarantine", defaultQuarantineDir, "Directory to write pages that panic to (xml format). Disabled when empty.")
+	flag.IntVar(&maxErrors, "max-errors", defaultMaxErrors, "Fail when there are more errors than this. Disabled when negative.")
 	flag.BoolVar(&noBackup, "no-backup", defaultNoBackup, "Whether to not backup index. Used when iterating on changes to index.")
 	flag.Parse()
 }
@@ -49,6 +62,16 @@ func main() {
 	}
 	errFile := (stat.Mode() & os.ModeCharDevice) == 0
 
+	var errorsEncoder *json.Encoder
+	if errorsOutputFile != "" {
+		f, err := os.Create(errorsOutputFile)
+		if err != nil {
+			log.Fatalf("Unable to create %s: %s", errorsOutputFile, err)
+		}
+		defer f.Close()
+		errorsEncoder = json.NewEncoder(f)
+	}
+
 	wordsCh := make(chan gt.Word, 10)
 	descendantsCh := make(chan gt.Descendants, 10)
 	errorsCh := make(chan gt.Error, 10)
@@ -56,10 +79,17 @@ func main() {
 
 	count := 0
 	descendantsCount := 0
+	errorsCount := 0
 	completed := 0
 
+	diagnostics := gt.NewDiagnostics()
+	opts := gt.ParseOptions{
+		Diagnostics:   diagnostics,
+		QuarantineDir: quarantineDir,
+	}
+
 	for _, f := range files {
-		go gt.ParseXMLWords(f, wordsCh, descendantsCh, errorsCh, doneCh)
+		go gt.ParseXMLWords(f, wordsCh, descendantsCh, errorsCh, doneCh, opts)
 	}
 
 	words := make(map[string]*gt.Word)
@@ -69,15 +99,21 @@ Loop:
 	for {
 		select {
 		case e := <-errorsCh:
-			if e.Fatal {
-				log.Fatalf("\nError parsing words: %s", e.Message)
+			if errorsEncoder != nil {
+				if err := errorsEncoder.Encode(e); err != nil {
+					log.Fatalf("\nUnable to write error to %s: %s", errorsOutputFile, err)
+				}
+			}
+			if e.IsFatal() {
+				log.Fatalf("\nError parsing words: %s", e)
 			} else {
 				var prefix string
 				if !errFile {
 					prefix = "\n"
 				}
-				fmt.Fprintf(os.Stderr, "%sError parsing words: %s\n", prefix, e.Message)
+				fmt.Fprintf(os.Stderr, "%sError parsing words: %s\n", prefix, e)
 			}
+			errorsCount++
 		case f := <-doneCh:
 			f.Close()
 			completed++
@@ -96,7 +132,11 @@ Loop:
 		}
 	}
 
-	fmt.Printf("\n%d total words, %d descendant trees\n", count, descendantsCount)
+	fmt.Printf("\n%d total words, %d descendant trees, %d errors\n", count, descendantsCount, errorsCount)
+
+	if maxErrors >= 0 && errorsCount > maxErrors {
+		log.Fatalf("Too many errors: %d (max %d)", errorsCount, maxErrors)
+	}
 
 	err = gt.WriteGob(outputFile, words, true, !noBackup)
 	if err != nil {
@@ -107,4 +147,9 @@ Loop:
 	if err != nil {
 		log.Fatalf("Unable to write and compress %s: %s", descendantsOutputFile, err)
 	}
+
+	err = diagnostics.WriteJSON(diagnosticsOutputFile)
+	if err != nil {
+		log.Fatalf("Unable to write %s: %s", diagnosticsOutputFile, err)
+	}
 }
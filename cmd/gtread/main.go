@@ -5,7 +5,6 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"os"
 	"strings"
 
 	"github.com/vthommeret/glossterm/lib/gt"
@@ -16,15 +15,15 @@ const defaultInput = "data/words.gob"
 var input string
 
 func init() {
-	flag.StringVar(&input, "i", defaultInput, "Input file (gob format)")
+	flag.StringVar(&input, "i", defaultInput, "Input file (gob format, or word store ending in .db)")
 	flag.Parse()
 }
 
 func main() {
-	if len(os.Args) < 2 {
+	if flag.NArg() < 1 {
 		log.Fatalf("Must specify word, e.g. es/helado.")
 	}
-	w := os.Args[1]
+	w := flag.Arg(0)
 
 	parts := strings.Split(w, "/")
 	if len(parts) < 2 {
@@ -34,19 +33,19 @@ func main() {
 	lang := parts[0]
 	word := parts[1]
 
-	words, err := gt.GetWords(input)
+	wd, err := gt.GetWord(input, word)
 	if err != nil {
-		log.Fatal("Unable to get %q words: %s", input, err)
+		log.Fatalf("Unable to get %q words: %s", input, err)
 	}
 
-	if _, ok := words[word]; !ok {
+	if wd == nil {
 		log.Fatalf("Unable to find word: %s", word)
 	}
-	if _, ok := words[word].Languages[lang]; !ok {
+	if _, ok := wd.Languages[lang]; !ok {
 		log.Fatalf("Unable to find language %s for word: %s", lang, word)
 	}
 
-	b, err := json.MarshalIndent(words[word].Languages[lang], "", "  ")
+	b, err := json.MarshalIndent(wd.Languages[lang], "", "  ")
 	if err != nil {
 		log.Fatalf("Unable to marshal JSON: %s", err)
 	}
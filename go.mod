@@ -7,7 +7,6 @@ require (
 	cloud.google.com/go/firestore v1.3.0
 	cloud.google.com/go/storage v1.11.0 // indirect
 	firebase.google.com/go v3.13.0+incompatible
-	github.com/apache/beam v2.24.0+incompatible
 	github.com/blevesearch/segment v0.9.0
 	github.com/cayleygraph/cayley v0.7.7
 	github.com/cayleygraph/quad v1.1.0
@@ -15,10 +14,12 @@ require (
 	github.com/google/go-cmp v0.5.2
 	github.com/sergi/go-diff v1.1.0
 	github.com/spf13/cobra v1.0.0 // indirect
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/net v0.0.0-20201031054903-ff519b6c9102
 	golang.org/x/text v0.3.3
 	golang.org/x/tools v0.0.0-20200917221617-d56e4e40bc9d // indirect
 	google.golang.org/api v0.32.0
 	google.golang.org/genproto v0.0.0-20200917134801-bb4cff56e0d0 // indirect
 	google.golang.org/grpc v1.32.0 // indirect
+	google.golang.org/protobuf v1.25.0
 )
@@ -1,83 +1,121 @@
 package gt
 
-import (
-	"fmt"
-	"log"
-	"strings"
-
-	"github.com/cayleygraph/cayley"
-	"github.com/cayleygraph/cayley/graph/path"
-	"github.com/cayleygraph/quad"
-)
+import "github.com/vthommeret/glossterm/lib/tpl"
 
 type Cognate struct {
 	Word string `json:"word" firestore:"word"`
 	From string `json:"from" firestore:"from"`
+	// Highest scoring path from the word to the cognate.
+	Path []CognateEdge `json:"path,omitempty" firestore:"path,omitempty"`
+	// Score of the path from 0 to 1, see CognateScorer.
+	Score float64 `json:"score,omitempty" firestore:"score,omitempty"`
+	// Set when the score is below the weak threshold given to FilterCognates.
+	Weak bool `json:"weak,omitempty" firestore:"weak,omitempty"`
 }
 
-func GetCognates(graph *cayley.Handle, lang string, word string) map[string]*Cognate {
-	prefix := fmt.Sprintf("%s/", lang)
-	w := quad.String(prefix + word)
-
-	s := cayley.StartPath(graph, w)
-	ps := findParents(s).Tag("parent")
-
-	// Find children of parent or second-degree parent
-	p := findChildren(findParents(ps).Tag("parent")).
-		Or(findChildren(ps))
-
-	rs, ts, err := QueryGraph(graph, p)
-	if err != nil {
-		log.Fatalf("Unable to execute query: %s", err)
-	}
+// CognateEdge is a quad on the path from a word to a cognate, e.g.
+// "es/hermano" inherited-from "la/germanus".
+type CognateEdge struct {
+	From      string `json:"from" firestore:"from"`
+	Predicate string `json:"predicate" firestore:"predicate"`
+	To        string `json:"to" firestore:"to"`
+}
 
+// GetCognates returns the cognates of a word in other languages, with the
+// highest scoring path to each using DefaultCognateScorer. Cognates are the
+// descendants of the word's parents or second-degree parents.
+func GetCognates(graph *Graph, lang string, word string) map[string]*Cognate {
 	cognates := map[string]*Cognate{}
-
-	for i, r := range rs {
-		if strings.HasPrefix(r, prefix) {
-			continue
+	for name, paths := range GetCognatePaths(graph, lang, word) {
+		var best []CognateEdge
+		score := -1.0
+		for _, path := range paths {
+			// Paths are shortest first, so ties go to the shortest.
+			if s := DefaultCognateScorer.Score(path); s > score {
+				best, score = path, s
+			}
+		}
+		cognates[name] = &Cognate{
+			Word:  name,
+			From:  best[len(best)-1].From,
+			Path:  best,
+			Score: score,
 		}
-		cognates[r] = &Cognate{Word: r, From: ts[i]}
 	}
-
 	return cognates
 }
 
-func findParents(p *path.Path) *path.Path {
-	return p.
-		Out("borrowing-from").
-		Or(p.Out("derived-from")).
-		Or(p.Out("inherited-from")).
-		Or(p.Out("mentions")).
-		Or(p.Out("etyl")).
-		Or(p.Out("suffix")).
-		Or(p.Out("cognate"))
+// GetCognatePaths returns every path from a word to each of its cognates,
+// shortest first. Each path goes through one or two parent quads and ends in
+// a descendant quad.
+func GetCognatePaths(graph *Graph, lang string, word string) map[string][][]CognateEdge {
+	return graph.Cousins(lang, word, 2)
 }
 
-func findChildren(p *path.Path) *path.Path {
-	return p.Out("descendant")
+// ParentPredicates are the quads from a word to the words it comes from.
+// Graph.Cousins follows one or two of them from a word, then a child quad, to
+// find its cognates.
+var ParentPredicates = []string{
+	"borrowing-from",
+	"derived-from",
+	"inherited-from",
+	"mentions",
+	"etyl",
+	"suffix",
+	"cognate",
+
+	// Loans, then words made of parts and same language relations.
+	LoanPredicate(tpl.LearnedBorrowing),
+	LoanPredicate(tpl.OrthographicBorrowing),
+	LoanPredicate(tpl.SemanticLoan),
+	LoanPredicate(tpl.Calque),
+	LoanPredicate(tpl.PhonoSemanticMatching),
+	tpl.AffixKind,
+	tpl.Compound,
+	tpl.Confix,
+	tpl.Blend,
+	tpl.Univerbation,
+	RelatedPredicate(tpl.BackFormation),
+	RelatedPredicate(tpl.Clipping),
 }
 
-/*
-
-// Gremlin query
+// ChildPredicate is the quad from a word to the words that come from it.
+const ChildPredicate = "descendant"
 
-var input = g.V("fr/pelouse")
+// Quads from a word to descendants listed with {{desc}}, by how they come
+// from it.
+const (
+	InheritedDescendantPredicate = "inherited-descendant"
+	BorrowedDescendantPredicate  = "borrowed-descendant"
+	DerivedDescendantPredicate   = "derived-descendant"
+)
 
-function findParents(g) {
-  return g.out("borrowing-from")
-    .or(g.out("derived-from"))
-    .or(g.out("inherited-from"))
-    .or(g.out("mentions"))
-    .or(g.out("etyl"))
-    .or(g.out("suffix"))
-    .or(g.out("cognate"))
+// ChildPredicates are every quad from a word to the words that come from it,
+// most specific first.
+var ChildPredicates = []string{
+	InheritedDescendantPredicate,
+	BorrowedDescendantPredicate,
+	DerivedDescendantPredicate,
+	ChildPredicate,
 }
 
-var parents = findParents(input)
-
-findParents(parents).out("descendant")
-  .or(parents.out("descendant"))
-  .all()
+// descendantPredicate returns the quad from a word to a descendant.
+func descendantPredicate(d tpl.Descendant) string {
+	switch DescendantRelation(d) {
+	case BorrowedDescendant:
+		return BorrowedDescendantPredicate
+	case DerivedDescendant:
+		return DerivedDescendantPredicate
+	}
+	return InheritedDescendantPredicate
+}
 
-*/
+// isChildPredicate returns whether a quad goes from a word to a descendant.
+func isChildPredicate(predicate string) bool {
+	for _, p := range ChildPredicates {
+		if p == predicate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package gt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// As written by cmd/gtquads for fr/pelouse, es/peloso and es/pelo.
+const cognateQuads = `"fr/pelouse" "borrowing-from" "la/pilosus" .
+"la/pilosus" "descendant" "fr/pelouse" .
+"es/peloso" "inherited-from" "la/pilosus" .
+"la/pilosus" "descendant" "es/peloso" .
+"la/pilosus" "derived-from" "la/pilus" .
+"la/pilus" "descendant" "la/pilosus" .
+"es/pelo" "inherited-from" "la/pilus" .
+"la/pilus" "descendant" "es/pelo" .
+"fr/pelouse" "mentions" "la/pilus" .
+"la/pilus" "descendant" "fr/pelouse" .
+`
+
+func TestGetCognates(t *testing.T) {
+	g := newTestGraph(t, cognateQuads)
+	got := GetCognates(g, "fr", "pelouse")
+
+	want := map[string]*Cognate{
+		"es/peloso": {
+			Word: "es/peloso",
+			From: "la/pilosus",
+			Path: []CognateEdge{
+				{From: "fr/pelouse", Predicate: "borrowing-from", To: "la/pilosus"},
+				{From: "la/pilosus", Predicate: "descendant", To: "es/peloso"},
+			},
+			Score: 0.9,
+		},
+		// The second-degree path through a borrowing scores higher than
+		// the mention.
+		"es/pelo": {
+			Word: "es/pelo",
+			From: "la/pilus",
+			Path: []CognateEdge{
+				{From: "fr/pelouse", Predicate: "borrowing-from", To: "la/pilosus"},
+				{From: "la/pilosus", Predicate: "derived-from", To: "la/pilus"},
+				{From: "la/pilus", Predicate: "descendant", To: "es/pelo"},
+			},
+			Score: 0.9 * 0.8 * 0.8,
+		},
+		"la/pilosus": {
+			Word: "la/pilosus",
+			From: "la/pilus",
+			Path: []CognateEdge{
+				{From: "fr/pelouse", Predicate: "borrowing-from", To: "la/pilosus"},
+				{From: "la/pilosus", Predicate: "derived-from", To: "la/pilus"},
+				{From: "la/pilus", Predicate: "descendant", To: "la/pilosus"},
+			},
+			Score: 0.9 * 0.8 * 0.8,
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateApprox(0, 1e-9)); diff != "" {
+		t.Errorf("gt.GetCognates(%q, %q) diff: %s", "fr", "pelouse", diff)
+	}
+
+	// es/pelo is also a second-degree cognate through la/pilosus.
+	paths := GetCognatePaths(g, "fr", "pelouse")["es/pelo"]
+	wantPaths := [][]CognateEdge{
+		{
+			{From: "fr/pelouse", Predicate: "mentions", To: "la/pilus"},
+			{From: "la/pilus", Predicate: "descendant", To: "es/pelo"},
+		},
+		{
+			{From: "fr/pelouse", Predicate: "borrowing-from", To: "la/pilosus"},
+			{From: "la/pilosus", Predicate: "derived-from", To: "la/pilus"},
+			{From: "la/pilus", Predicate: "descendant", To: "es/pelo"},
+		},
+	}
+	if diff := cmp.Diff(wantPaths, paths); diff != "" {
+		t.Errorf("gt.GetCognatePaths(%q, %q)[%q] diff: %s", "fr", "pelouse", "es/pelo", diff)
+	}
+}
+
+func TestFilterCognates(t *testing.T) {
+	cognates := []*Cognate{
+		{Word: "es/peloso", Score: 0.9},
+		{Word: "es/pelo", Score: 0.4},
+		{Word: "it/peloso", Score: 0.1},
+	}
+	got := FilterCognates(cognates, 0.2, 0.5)
+	want := []*Cognate{
+		{Word: "es/peloso", Score: 0.9},
+		{Word: "es/pelo", Score: 0.4, Weak: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("gt.FilterCognates(...) diff: %s", diff)
+	}
+}
@@ -0,0 +1,225 @@
+package gt
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// CognateOptions configure ComputeCognates.
+type CognateOptions struct {
+	// Number of words to find cognates for at once, runtime.NumCPU() by
+	// default.
+	Workers int
+
+	// Cognates scoring below MinScore are dropped and below WeakScore are
+	// marked as weak, see FilterCognates.
+	MinScore  float64
+	WeakScore float64
+
+	// Number of changed words written at once, 1000 by default.
+	BatchSize int
+
+	// Progress is called every ProgressInterval words, 1000 by default, and
+	// once all are done, with the number of words done so far and the total.
+	Progress         func(done, total int)
+	ProgressInterval int
+}
+
+const (
+	defaultCognateBatchSize        = 1000
+	defaultCognateProgressInterval = 1000
+)
+
+// CognateStats count the words and cognates found by ComputeCognates.
+type CognateStats struct {
+	Words        int // words in source languages
+	WithCognates int // words with at least one cognate
+	Cognates     int
+	Changed      int // words written back
+}
+
+type cognateResult struct {
+	word     *Word
+	cognates int
+	changed  bool
+	err      error
+}
+
+// ComputeCognates finds the cognates of every word in a source language and
+// writes the words whose cognates changed back to words. It stops at the first
+// error or when ctx is cancelled.
+func ComputeCognates(ctx context.Context, graph *Graph, words Words, opts CognateOptions) (CognateStats, error) {
+	var stats CognateStats
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCognateBatchSize
+	}
+	progressInterval := opts.ProgressInterval
+	if progressInterval <= 0 {
+		progressInterval = defaultCognateProgressInterval
+	}
+
+	names, err := words.Names()
+	if err != nil {
+		return stats, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan string)
+	go func() {
+		defer close(jobs)
+		for _, name := range names {
+			select {
+			case jobs <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan cognateResult)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				r := cognateResult{}
+				r.word, r.err = words.Get(name)
+				if r.err == nil && r.word != nil {
+					r.cognates, r.changed = setCognates(graph, r.word, opts.MinScore, opts.WeakScore)
+				}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Write changed words from this goroutine so stores only have one writer.
+	var batch []*Word
+	done := 0
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := words.Put(batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+		return nil
+	}
+	progress := func() {
+		if opts.Progress != nil {
+			opts.Progress(done, len(names))
+		}
+	}
+	for r := range results {
+		if r.err != nil {
+			return stats, r.err
+		}
+		done++
+		if done%progressInterval == 0 {
+			progress()
+		}
+		if r.word == nil {
+			continue
+		}
+		if hasSourceLang(r.word) {
+			stats.Words++
+		}
+		if r.cognates > 0 {
+			stats.WithCognates++
+			stats.Cognates += r.cognates
+		}
+		if r.changed {
+			stats.Changed++
+			batch = append(batch, r.word)
+		}
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+	if done == 0 || done%progressInterval != 0 {
+		progress()
+	}
+	return stats, nil
+}
+
+func hasSourceLang(w *Word) bool {
+	for code := range w.Languages {
+		if SourceLangs[code] {
+			return true
+		}
+	}
+	return false
+}
+
+// setCognates sets the cognates of each source language of a word, returning
+// the number of cognates and whether they changed.
+func setCognates(graph *Graph, w *Word, minScore, weakScore float64) (int, bool) {
+	count := 0
+	changed := false
+	for code, l := range w.Languages {
+		if !SourceLangs[code] {
+			continue
+		}
+		cognateMap := GetCognates(graph, code, w.Name)
+		var names []string
+		for name := range cognateMap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var cognates []*Cognate
+		for _, name := range names {
+			cognates = append(cognates, cognateMap[name])
+		}
+		cognates = FilterCognates(cognates, minScore, weakScore)
+
+		count += len(cognates)
+		if !equalCognates(l.Cognates, cognates) {
+			l.Cognates = cognates
+			changed = true
+		}
+	}
+	return count, changed
+}
+
+func equalCognates(a, b []*Cognate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Word != b[i].Word || a[i].From != b[i].From || a[i].Score != b[i].Score || a[i].Weak != b[i].Weak || len(a[i].Path) != len(b[i].Path) {
+			return false
+		}
+		for j := range a[i].Path {
+			if a[i].Path[j] != b[i].Path[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package gt
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func cognateTestWords() map[string]*Word {
+	return map[string]*Word{
+		"pelouse": {Name: "pelouse", Languages: map[string]*Language{"fr": {Code: "fr"}}},
+		"peloso":  {Name: "peloso", Languages: map[string]*Language{"es": {Code: "es"}}},
+		"pelo":    {Name: "pelo", Languages: map[string]*Language{"es": {Code: "es"}}},
+		"pilosus": {Name: "pilosus", Languages: map[string]*Language{"la": {Code: "la"}}},
+	}
+}
+
+func TestComputeCognates(t *testing.T) {
+	g := newTestGraph(t, cognateQuads)
+	words := cognateTestWords()
+
+	var progress []int
+	stats, err := ComputeCognates(context.Background(), g, NewWordMap(words), CognateOptions{
+		Workers:   2,
+		BatchSize: 1,
+		MinScore:  0.5,
+		Progress: func(done, total int) {
+			if total != len(words) {
+				t.Errorf("Progress got total %d, want %d.", total, len(words))
+			}
+			progress = append(progress, done)
+		},
+	})
+	if err != nil {
+		t.Fatalf("gt.ComputeCognates(...) got error: %s.", err)
+	}
+
+	// Each word has the other two as cognates, plus la/pilosus.
+	want := CognateStats{Words: 3, WithCognates: 3, Cognates: 7, Changed: 3}
+	if stats != want {
+		t.Errorf("gt.ComputeCognates(...) got %+v, want %+v.", stats, want)
+	}
+	var got []string
+	for _, c := range words["pelouse"].Languages["fr"].Cognates {
+		got = append(got, c.Word)
+	}
+	if len(got) != 3 || got[0] != "es/pelo" || got[1] != "es/peloso" || got[2] != "la/pilosus" {
+		t.Errorf("gt.ComputeCognates(...) got cognates %q for fr/pelouse.", got)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != len(words) {
+		t.Errorf("gt.ComputeCognates(...) got progress %v, want it to end at %d.", progress, len(words))
+	}
+
+	// Nothing changes the second time, but progress is still reported.
+	progress = nil
+	stats, err = ComputeCognates(context.Background(), g, NewWordMap(words), CognateOptions{
+		MinScore:         0.5,
+		ProgressInterval: 2,
+		Progress: func(done, total int) {
+			progress = append(progress, done)
+		},
+	})
+	if err != nil {
+		t.Fatalf("gt.ComputeCognates(...) got error: %s.", err)
+	}
+	if stats.Changed != 0 {
+		t.Errorf("gt.ComputeCognates(...) changed %d words again, want 0.", stats.Changed)
+	}
+	if len(progress) != 2 || progress[0] != 2 || progress[1] != 4 {
+		t.Errorf("gt.ComputeCognates(...) got progress %v, want [2 4].", progress)
+	}
+}
+
+func TestComputeCognatesStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cognates")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "words.db")
+	ws, err := OpenWordStore(path)
+	if err != nil {
+		t.Fatalf("gt.OpenWordStore(%q) got error: %s.", path, err)
+	}
+	defer ws.Close()
+	if err := ws.PutWords(cognateTestWords()); err != nil {
+		t.Fatalf("Unable to put words: %s", err)
+	}
+
+	_, err = ComputeCognates(context.Background(), newTestGraph(t, cognateQuads), ws, CognateOptions{Workers: 4, BatchSize: 1})
+	if err != nil {
+		t.Fatalf("gt.ComputeCognates(...) got error: %s.", err)
+	}
+	w, err := ws.Get("peloso")
+	if err != nil {
+		t.Fatalf("Unable to get %q: %s", "peloso", err)
+	}
+	var got []string
+	for _, c := range w.Languages["es"].Cognates {
+		got = append(got, c.Word)
+	}
+	if len(got) != 2 || got[0] != "fr/pelouse" || got[1] != "la/pilosus" {
+		t.Errorf("gt.ComputeCognates(...) got cognates %q for es/peloso, want fr/pelouse and la/pilosus.", got)
+	}
+}
+
+func TestComputeCognatesCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ComputeCognates(ctx, newTestGraph(t, cognateQuads), NewWordMap(cognateTestWords()), CognateOptions{})
+	if err != context.Canceled {
+		t.Errorf("gt.ComputeCognates(...) got error %v, want %v.", err, context.Canceled)
+	}
+}
@@ -0,0 +1,357 @@
+package gt
+
+import (
+	"strings"
+
+	"github.com/vthommeret/glossterm/lib/lang"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+// Default number of levels of desctrees resolved by ResolveDescTrees.
+const DefaultDescTreeDepth = 10
+
+// How a descendant comes from the word above it, from the {{desc}} flags.
+const (
+	InheritedDescendant = "inherited"
+	BorrowedDescendant  = "borrowed"
+	DerivedDescendant   = "derived"
+)
+
+// DescendantNode is a descendant with the descendants that come from it,
+// e.g. Old French "frere" with French "frère" below it.
+type DescendantNode struct {
+	Lang string `json:"lang" firestore:"lang"`
+	// Empty for a language listed without a word, e.g. "* Old Spanish:"
+	// with descendants below it.
+	Word     string `json:"word,omitempty" firestore:"word,omitempty"`
+	Depth    int    `json:"depth" firestore:"depth"` // 1 for the word's own descendants
+	Relation string `json:"relation,omitempty" firestore:"relation,omitempty"`
+	// Set for {{desctree}}, whose descendants are listed by its own entry.
+	DescTree bool `json:"descTree,omitempty" firestore:"descTree,omitempty"`
+	// Set when the descendant is also an ancestor, so it isn't resolved again.
+	Cycle    bool              `json:"cycle,omitempty" firestore:"cycle,omitempty"`
+	Children []*DescendantNode `json:"children,omitempty" firestore:"children,omitempty"`
+}
+
+// Descendants returns every descendant below the node, depth first.
+func (n *DescendantNode) Descendants() []tpl.Descendant {
+	return flattenDescendants(n.Children)
+}
+
+func flattenDescendants(nodes []*DescendantNode) []tpl.Descendant {
+	var ds []tpl.Descendant
+	for _, n := range nodes {
+		if n.Word != "" {
+			ds = append(ds, tpl.Descendant{Lang: n.Lang, Word: n.Word})
+		}
+		ds = append(ds, flattenDescendants(n.Children)...)
+	}
+	return ds
+}
+
+// copyDescendantNodes returns a deep copy of nodes, adding offset to each
+// depth.
+func copyDescendantNodes(nodes []*DescendantNode, offset int) []*DescendantNode {
+	var copied []*DescendantNode
+	for _, n := range nodes {
+		c := *n
+		c.Depth += offset
+		c.Children = copyDescendantNodes(n.Children, offset)
+		copied = append(copied, &c)
+	}
+	return copied
+}
+
+// descendantTreeBuilder builds a DescendantNode tree from the nested list of
+// a Descendants section, e.g. "* Old French: {{desc|fro|frere}}" followed by
+// "** French: {{desc|fr|frère}}".
+type descendantTreeBuilder struct {
+	roots *[]*DescendantNode
+	// Last node at each list depth.
+	stack []*DescendantNode
+	// The current list item's depth, whether it has a node, and the
+	// language of its "Old French:" prefix.
+	inItem    bool
+	itemDepth int
+	hasNode   bool
+	itemLang  string
+}
+
+// reset starts a new Descendants section.
+func (b *descendantTreeBuilder) reset() {
+	b.stack = nil
+	b.inItem = false
+	b.hasNode = false
+}
+
+// startItem starts a list item at a list depth.
+func (b *descendantTreeBuilder) startItem(depth int) {
+	b.inItem = true
+	b.itemDepth = depth
+	b.hasNode = false
+	b.itemLang = ""
+}
+
+// text reads the language from a list item's prefix before its first term.
+func (b *descendantTreeBuilder) text(s string, langMap map[string]bool) {
+	if !b.inItem || b.hasNode || b.itemLang != "" {
+		return
+	}
+	s = strings.TrimSuffix(strings.TrimSpace(s), ":")
+	if l, ok := lang.CanonicalLangs[s]; ok && langMap[l.Code] {
+		b.itemLang = l.Code
+	}
+}
+
+// endItem ends a list item, adding its language if it had no terms so
+// descendants below it keep their intermediate language.
+func (b *descendantTreeBuilder) endItem() {
+	if b.inItem && !b.hasNode && b.itemLang != "" {
+		b.add(b.itemDepth, &DescendantNode{Lang: b.itemLang})
+	}
+	b.inItem = false
+	b.hasNode = false
+}
+
+// add adds a node at a list depth. Further terms in the same list item are
+// added alongside the first, which the next level is added below.
+func (b *descendantTreeBuilder) add(depth int, n *DescendantNode) {
+	if depth < 1 {
+		depth = 1
+	}
+	if b.hasNode {
+		b.attach(len(b.stack)-1, n)
+		return
+	}
+	b.hasNode = true
+	if len(b.stack) > depth-1 {
+		b.stack = b.stack[:depth-1]
+	}
+	b.attach(len(b.stack), n)
+	b.stack = append(b.stack, n)
+}
+
+// attach adds n below the node at index i-1 of the stack, or as a root.
+func (b *descendantTreeBuilder) attach(i int, n *DescendantNode) {
+	n.Depth = i + 1
+	if i == 0 {
+		*b.roots = append(*b.roots, n)
+		return
+	}
+	parent := b.stack[i-1]
+	parent.Children = append(parent.Children, n)
+}
+
+// DescendantRelation returns how a {{desc}} or {{desctree}} descendant comes
+// from the word above it.
+func DescendantRelation(d tpl.Descendant) string {
+	switch {
+	case d.Borrowing():
+		return BorrowedDescendant
+	case d.Derived:
+		return DerivedDescendant
+	}
+	return InheritedDescendant
+}
+
+// DescTreeStats count the desctrees seen by ResolveDescTrees.
+type DescTreeStats struct {
+	Resolved   int // desctrees whose entry was found
+	Unresolved int // desctrees without an entry listing descendants
+	Cycles     int // desctrees leading back to one of their ancestors
+	Truncated  int // desctrees deeper than the max depth
+}
+
+type descTreeResolver struct {
+	// Descendants listed by each entry (lang/word) before any are
+	// resolved, so results don't depend on the order words are resolved.
+	entries  map[string][]*DescendantNode
+	maxDepth int
+	stats    DescTreeStats
+}
+
+// ResolveDescTrees resolves {{desctree}} references by adding the
+// descendants listed by the referenced entry below them in DescendantTree,
+// recursively up to maxDepth levels (or no limit if negative). The
+// descendants found are also added to Descendants.
+func ResolveDescTrees(words map[string]*Word, maxDepth int) DescTreeStats {
+	return resolveDescTrees(words, nil, maxDepth)
+}
+
+// resolveDescTrees resolves the desctrees of the named words, or of all words
+// if names is nil, against the descendants every word lists.
+func resolveDescTrees(words map[string]*Word, names map[string]bool, maxDepth int) DescTreeStats {
+	r := &descTreeResolver{
+		entries:  map[string][]*DescendantNode{},
+		maxDepth: maxDepth,
+	}
+	for _, w := range words {
+		for _, l := range w.Languages {
+			// Words parsed before trees were kept only have a flat list.
+			if l.DescendantTree == nil && len(l.Descendants) > 0 {
+				l.DescendantTree = flatDescendantTree(l)
+			}
+			if len(l.DescendantTree) > 0 {
+				r.entries[l.Code+"/"+w.Name] = copyDescendantNodes(l.DescendantTree, 0)
+			}
+		}
+	}
+
+	for name, w := range words {
+		if names != nil && !names[name] {
+			continue
+		}
+		for _, l := range w.Languages {
+			if len(l.DescTrees) == 0 {
+				continue
+			}
+			r.expand(l.DescendantTree, 1, map[string]bool{l.Code + "/" + w.Name: true})
+			l.Descendants = UniqueDescendants(append(l.Descendants, flattenDescendants(l.DescendantTree)...))
+		}
+	}
+	return r.stats
+}
+
+// ResolveEtymTrees adds the links and descendants of the (legacy) etymtrees
+// each language refers to, returning how many were resolved.
+func ResolveEtymTrees(words map[string]*Word, descendants map[string]Descendants) int {
+	resolved := 0
+	for _, w := range words {
+		for _, l := range w.Languages {
+			resolved += l.addEtymTrees(descendants, nil)
+		}
+	}
+	return resolved
+}
+
+// addEtymTrees adds the links and descendants of the language's etymtrees,
+// or only of those named if names isn't nil, returning how many were added.
+func (l *Language) addEtymTrees(descendants map[string]Descendants, names map[string]bool) int {
+	added := 0
+	for _, t := range l.DescendantTrees {
+		n := t.ToEntryName()
+		if names != nil && !names[n] {
+			continue
+		}
+		if ds, ok := descendants[n]; ok {
+			l.Links = append(l.Links, ds.Links...)
+			l.Descendants = append(l.Descendants, ds.Descendants...)
+			added++
+		}
+	}
+	if added > 0 {
+		l.Descendants = UniqueDescendants(l.Descendants)
+	}
+	return added
+}
+
+// removeEtymTree removes the links and descendants an etymtree added, taking
+// the last of each since they were added after those the entry lists.
+func (l *Language) removeEtymTree(ds Descendants) {
+	for i := len(ds.Links) - 1; i >= 0; i-- {
+		for j := len(l.Links) - 1; j >= 0; j-- {
+			if l.Links[j] == ds.Links[i] {
+				l.Links = append(l.Links[:j], l.Links[j+1:]...)
+				break
+			}
+		}
+	}
+	for i := len(ds.Descendants) - 1; i >= 0; i-- {
+		d := ds.Descendants[i]
+		for j := len(l.Descendants) - 1; j >= 0; j-- {
+			if l.Descendants[j].Lang == d.Lang && l.Descendants[j].Word == d.Word {
+				l.Descendants = append(l.Descendants[:j], l.Descendants[j+1:]...)
+				break
+			}
+		}
+	}
+	if len(l.Links) == 0 {
+		l.Links = nil
+	}
+	if len(l.Descendants) == 0 {
+		l.Descendants = nil
+	}
+}
+
+// flatDescendantTree returns a node for each of a language's descendants.
+func flatDescendantTree(l *Language) []*DescendantNode {
+	descTrees := map[string]bool{}
+	for _, dt := range l.DescTrees {
+		descTrees[dt.Lang+"/"+dt.Word] = true
+	}
+	var nodes []*DescendantNode
+	for _, d := range l.Descendants {
+		nodes = append(nodes, &DescendantNode{
+			Lang:     d.Lang,
+			Word:     d.Word,
+			Depth:    1,
+			DescTree: descTrees[d.Lang+"/"+d.Word],
+		})
+	}
+	return nodes
+}
+
+// expand resolves the desctrees in nodes, which are level desctrees deep.
+// path is the desctrees above them.
+func (r *descTreeResolver) expand(nodes []*DescendantNode, level int, path map[string]bool) {
+	for _, n := range nodes {
+		if n.DescTree {
+			r.resolve(n, level, path)
+		} else {
+			r.expand(n.Children, level, path)
+		}
+	}
+}
+
+// resolve adds the descendants listed by a desctree's entry below it.
+func (r *descTreeResolver) resolve(n *DescendantNode, level int, path map[string]bool) {
+	key := n.Lang + "/" + n.Word
+	if path[key] {
+		n.Cycle = true
+		r.stats.Cycles++
+		return
+	}
+	nodes, ok := r.entries[key]
+	if !ok {
+		r.stats.Unresolved++
+		return
+	}
+	if r.maxDepth >= 0 && level > r.maxDepth {
+		r.stats.Truncated++
+		return
+	}
+	r.stats.Resolved++
+
+	// Keep descendants already listed below the desctree.
+	listed := map[string]bool{}
+	for _, c := range n.Children {
+		listed[c.Lang+"/"+c.Word] = true
+	}
+	var added []*DescendantNode
+	for _, c := range copyDescendantNodes(nodes, n.Depth) {
+		if !listed[c.Lang+"/"+c.Word] {
+			added = append(added, c)
+		}
+	}
+	n.Children = append(n.Children, added...)
+
+	path[key] = true
+	r.expand(added, level+1, path)
+	delete(path, key)
+}
+
+// UniqueDescendants returns descendants without duplicates, keeping the
+// first of each.
+func UniqueDescendants(ds []tpl.Descendant) []tpl.Descendant {
+	var unique []tpl.Descendant
+	seen := map[string]bool{}
+	for _, d := range ds {
+		key := d.Lang + "/" + d.Word
+		if seen[key] {
+			continue
+		}
+		unique = append(unique, d)
+		seen[key] = true
+	}
+	return unique
+}
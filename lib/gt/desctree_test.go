@@ -0,0 +1,114 @@
+package gt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+func descTreeTestWords() map[string]*Word {
+	return map[string]*Word{
+		"germanus": {Name: "germanus", Languages: map[string]*Language{"la": {
+			Code: "la",
+			Descendants: []tpl.Descendant{
+				{Lang: "fro", Word: "germain"},
+				{Lang: "es", Word: "hermano"},
+			},
+			DescTrees: []tpl.DescTree{{Lang: "fro", Word: "germain"}},
+			DescendantTree: []*DescendantNode{
+				{Lang: "fro", Word: "germain", Depth: 1, DescTree: true},
+				{Lang: "es", Word: "hermano", Depth: 1},
+			},
+		}}},
+		"germain": {Name: "germain", Languages: map[string]*Language{"fro": {
+			Code: "fro",
+			Descendants: []tpl.Descendant{
+				{Lang: "fr", Word: "germain"},
+				{Lang: "enm", Word: "germain"},
+				{Lang: "la", Word: "germanus"},
+			},
+			DescTrees: []tpl.DescTree{{Lang: "enm", Word: "germain"}, {Lang: "la", Word: "germanus"}},
+			DescendantTree: []*DescendantNode{
+				{Lang: "fr", Word: "germain", Depth: 1},
+				{Lang: "enm", Word: "germain", Depth: 1, DescTree: true},
+				{Lang: "la", Word: "germanus", Depth: 1, DescTree: true},
+			},
+		}, "enm": {
+			// Parsed before trees were kept.
+			Code: "enm",
+			Descendants: []tpl.Descendant{
+				{Lang: "en", Word: "german"},
+				{Lang: "en", Word: "missing"},
+			},
+			DescTrees: []tpl.DescTree{{Lang: "en", Word: "missing"}},
+		}}},
+	}
+}
+
+func TestResolveDescTrees(t *testing.T) {
+	words := descTreeTestWords()
+	stats := ResolveDescTrees(words, DefaultDescTreeDepth)
+
+	l := words["germanus"].Languages["la"]
+	wantTree := []*DescendantNode{
+		{
+			Lang:     "fro",
+			Word:     "germain",
+			Depth:    1,
+			DescTree: true,
+			Children: []*DescendantNode{
+				{Lang: "fr", Word: "germain", Depth: 2},
+				{Lang: "enm", Word: "germain", Depth: 2, DescTree: true, Children: []*DescendantNode{
+					{Lang: "en", Word: "german", Depth: 3},
+					{Lang: "en", Word: "missing", Depth: 3, DescTree: true},
+				}},
+				// Back to la/germanus.
+				{Lang: "la", Word: "germanus", Depth: 2, DescTree: true, Cycle: true},
+			},
+		},
+		{Lang: "es", Word: "hermano", Depth: 1},
+	}
+	if diff := cmp.Diff(wantTree, l.DescendantTree); diff != "" {
+		t.Errorf("gt.ResolveDescTrees(...) la/germanus tree diff: %s", diff)
+	}
+
+	wantDescendants := []tpl.Descendant{
+		{Lang: "fro", Word: "germain"},
+		{Lang: "es", Word: "hermano"},
+		{Lang: "fr", Word: "germain"},
+		{Lang: "enm", Word: "germain"},
+		{Lang: "en", Word: "german"},
+		{Lang: "en", Word: "missing"},
+		{Lang: "la", Word: "germanus"},
+	}
+	if diff := cmp.Diff(wantDescendants, l.Descendants); diff != "" {
+		t.Errorf("gt.ResolveDescTrees(...) la/germanus descendants diff: %s", diff)
+	}
+
+	// en/missing is reached from each word and the cycle from two.
+	wantStats := DescTreeStats{Resolved: 4, Unresolved: 3, Cycles: 2}
+	if stats != wantStats {
+		t.Errorf("gt.ResolveDescTrees(...) got %+v, want %+v.", stats, wantStats)
+	}
+}
+
+func TestResolveDescTreesDepth(t *testing.T) {
+	words := descTreeTestWords()
+	stats := ResolveDescTrees(words, 1)
+
+	// Siblings each get the full depth rather than sharing it.
+	tree := words["germain"].Languages["fro"].DescendantTree
+	if len(tree) != 3 || len(tree[1].Children) != 2 || len(tree[2].Children) != 2 {
+		t.Errorf("gt.ResolveDescTrees(..., 1) got fro/germain tree %+v.", tree)
+	}
+
+	// enm/germain is listed but not resolved below fro/germain.
+	tree = words["germanus"].Languages["la"].DescendantTree
+	if n := tree[0].Children[1]; n.Word != "germain" || n.Children != nil {
+		t.Errorf("gt.ResolveDescTrees(..., 1) got la/germanus tree node %+v, want no children.", n)
+	}
+	if stats.Truncated != 1 {
+		t.Errorf("gt.ResolveDescTrees(..., 1) got %+v, want 1 truncated.", stats)
+	}
+}
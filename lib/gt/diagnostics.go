@@ -0,0 +1,122 @@
+package gt
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"sync"
+)
+
+type DiagnosticKind string
+
+const (
+	UnknownTemplate  DiagnosticKind = "unknownTemplates"
+	UnhandledSection DiagnosticKind = "unhandledSections"
+	UnknownLabel     DiagnosticKind = "unknownLabels"
+	UnknownFormOf    DiagnosticKind = "unknownFormOf"
+)
+
+// Number of example page titles kept for each diagnostic.
+const maxDiagnosticExamples = 5
+
+// Diagnostics counts what the parser saw but didn't handle, by kind,
+// language and name. It's safe for concurrent use and a nil *Diagnostics
+// ignores everything.
+type Diagnostics struct {
+	mu     sync.Mutex
+	counts map[DiagnosticKind]map[string]map[string]*DiagnosticCount
+}
+
+// DiagnosticCount is how often a name was seen, with example page titles.
+type DiagnosticCount struct {
+	Name     string   `json:"name"`
+	Count    int      `json:"count"`
+	Examples []string `json:"examples"`
+}
+
+// DiagnosticsReport is diagnostic counts by kind and language, most frequent
+// first.
+type DiagnosticsReport map[DiagnosticKind]map[string][]DiagnosticCount
+
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{
+		counts: map[DiagnosticKind]map[string]map[string]*DiagnosticCount{},
+	}
+}
+
+// Add counts name for kind and language on the given page.
+func (d *Diagnostics) Add(kind DiagnosticKind, lang, name, title string) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	langs, ok := d.counts[kind]
+	if !ok {
+		langs = map[string]map[string]*DiagnosticCount{}
+		d.counts[kind] = langs
+	}
+	names, ok := langs[lang]
+	if !ok {
+		names = map[string]*DiagnosticCount{}
+		langs[lang] = names
+	}
+	c, ok := names[name]
+	if !ok {
+		c = &DiagnosticCount{Name: name}
+		names[name] = c
+	}
+	c.Count++
+	if len(c.Examples) < maxDiagnosticExamples && !containsString(c.Examples, title) {
+		c.Examples = append(c.Examples, title)
+	}
+}
+
+// Report returns the counts sorted by frequency.
+func (d *Diagnostics) Report() DiagnosticsReport {
+	r := DiagnosticsReport{}
+	if d == nil {
+		return r
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for kind, langs := range d.counts {
+		r[kind] = map[string][]DiagnosticCount{}
+		for lang, names := range langs {
+			var counts []DiagnosticCount
+			for _, c := range names {
+				counts = append(counts, *c)
+			}
+			sort.Slice(counts, func(i, j int) bool {
+				if counts[i].Count != counts[j].Count {
+					return counts[i].Count > counts[j].Count
+				}
+				return counts[i].Name < counts[j].Name
+			})
+			r[kind][lang] = counts
+		}
+	}
+	return r
+}
+
+// WriteJSON writes the report to the given path.
+func (d *Diagnostics) WriteJSON(p string) error {
+	b, err := json.MarshalIndent(d.Report(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p, b, 0644)
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
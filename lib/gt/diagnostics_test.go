@@ -0,0 +1,44 @@
+package gt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vthommeret/glossterm/lib/lang"
+)
+
+func TestDiagnostics(t *testing.T) {
+	pages := []Page{
+		{Title: "libro", Text: "==Spanish==\n\n===Etymology===\n{{unknown|es}}\n\n===Pronunciation===\n\n===Noun===\n{{es-noun|m}}\n# {{lb|es|rare sense|made-up}} {{made-up of|es|x}}"},
+		{Title: "libros", Text: "==Spanish==\n\n===Noun===\n# {{made-up of|es|x}} {{other}}"},
+	}
+
+	d := NewDiagnostics()
+	for _, p := range pages {
+		if _, err := ParseWordDiagnostics(p, lang.DefaultLangMap, d); err != nil {
+			t.Fatalf("gt.ParseWordDiagnostics(%q) got error: %s.", p.Title, err)
+		}
+	}
+
+	want := DiagnosticsReport{
+		UnknownTemplate: {
+			"es": {
+				{Name: "other", Count: 1, Examples: []string{"libros"}},
+				{Name: "unknown", Count: 1, Examples: []string{"libro"}},
+			},
+		},
+		UnhandledSection: {
+			"es": {{Name: "Pronunciation", Count: 1, Examples: []string{"libro"}}},
+		},
+		UnknownLabel: {
+			"es": {{Name: "made-up", Count: 1, Examples: []string{"libro"}}},
+		},
+		UnknownFormOf: {
+			"es": {{Name: "made-up of", Count: 2, Examples: []string{"libro", "libros"}}},
+		},
+	}
+
+	if diff := cmp.Diff(want, d.Report()); diff != "" {
+		t.Errorf("Diagnostics.Report() diff: %s", diff)
+	}
+}
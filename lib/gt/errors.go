@@ -0,0 +1,121 @@
+package gt
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+type ErrorCategory string
+
+const (
+	XMLError   ErrorCategory = "xml"   // the dump couldn't be decoded
+	LexError   ErrorCategory = "lex"   // the lexer panicked on a page, malformed input is lexed as text
+	ParseError ErrorCategory = "parse" // the parser couldn't handle a page
+	PanicError ErrorCategory = "panic" // the parser panicked on a page
+)
+
+type Severity string
+
+const (
+	FatalSeverity Severity = "fatal" // parsing can't continue
+	ErrorSeverity Severity = "error" // a page was skipped
+)
+
+// Error is an error parsing the dump or a page. Offset, Line and Token are
+// set when the error comes from a position within the page text.
+type Error struct {
+	Message  string        `json:"message"`
+	Title    string        `json:"title,omitempty"`
+	Offset   int           `json:"offset,omitempty"`
+	Line     int           `json:"line,omitempty"`
+	Token    string        `json:"token,omitempty"`
+	Category ErrorCategory `json:"category"`
+	Severity Severity      `json:"severity"`
+
+	// Set for panics.
+	Stack       string `json:"stack,omitempty"`
+	Quarantined string `json:"quarantined,omitempty"` // file with the page XML
+}
+
+func (e Error) Error() string {
+	var parts []string
+	if e.Title != "" {
+		parts = append(parts, fmt.Sprintf("%q", e.Title))
+	}
+	if e.Line > 0 {
+		parts = append(parts, fmt.Sprintf("line %d (offset %d)", e.Line, e.Offset))
+	}
+	if e.Token != "" {
+		parts = append(parts, fmt.Sprintf("at %q", e.Token))
+	}
+	if len(parts) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(parts, " "), e.Message)
+}
+
+// IsFatal returns whether parsing can't continue after the error.
+func (e Error) IsFatal() bool {
+	return e.Severity == FatalSeverity
+}
+
+// Maximum length of the input shown for lexer errors.
+const maxErrorToken = 20
+
+// errorAt returns an error for an item from the lexer. The token is the item,
+// or the input at the item for lexer errors.
+func (l *lexer) errorAt(title string, category ErrorCategory, i item, message string) Error {
+	offset, line := l.position(i.pos)
+	token := i.val
+	if i.typ == itemError {
+		token = l.input[i.pos:]
+		if n := strings.IndexByte(token, '\n'); n >= 0 {
+			token = token[:n]
+		}
+		if len(token) > maxErrorToken {
+			token = token[:maxErrorToken]
+		}
+	}
+	e := Error{
+		Message:  message,
+		Title:    title,
+		Offset:   offset,
+		Line:     line,
+		Token:    token,
+		Category: category,
+		Severity: ErrorSeverity,
+	}
+	if l.panicStack != "" {
+		e.Stack = l.panicStack
+	}
+	return e
+}
+
+// panicError returns an error for a panic while parsing a page.
+func panicError(title string, r interface{}) Error {
+	return Error{
+		Message:  fmt.Sprintf("panic: %v", r),
+		Title:    title,
+		Category: PanicError,
+		Severity: ErrorSeverity,
+		Stack:    string(debug.Stack()),
+	}
+}
+
+// pageError returns an error for a page that couldn't be parsed, keeping the
+// details of structured errors.
+func pageError(title string, err error) Error {
+	if e, ok := err.(Error); ok {
+		if e.Title == "" {
+			e.Title = title
+		}
+		return e
+	}
+	return Error{
+		Message:  err.Error(),
+		Title:    title,
+		Category: ParseError,
+		Severity: ErrorSeverity,
+	}
+}
@@ -0,0 +1,77 @@
+package gt
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vthommeret/glossterm/lib/lang"
+)
+
+// Longest a single input may take before it's considered to not terminate.
+const fuzzTimeout = 5 * time.Second
+
+var etymTreeSeeds = []string{
+	"* Latin: {{l|la|germanus}}\n** French: {{l|fr|germain}}\n** Spanish: {{l|es|hermano}}",
+	"{{top2}}\n* {{desc|es|hermano}}\n*: {{desc|pt|irmão}}\n{{bottom}}",
+}
+
+// terminates runs f on the test goroutine, so panics and t.Fatalf within it
+// are reported against the input. A watchdog crashes the fuzzer if f doesn't
+// return in time, since a hung input can't otherwise be recovered from.
+func terminates(t *testing.T, input string, f func()) {
+	watchdog := time.AfterFunc(fuzzTimeout, func() {
+		panic(fmt.Sprintf("%q did not terminate after %s.", input, fuzzTimeout))
+	})
+	defer watchdog.Stop()
+	f()
+}
+
+func FuzzLexer(f *testing.F) {
+	for _, tt := range lexTests {
+		f.Add(tt.input)
+	}
+	for _, tt := range parseTests {
+		f.Add(tt.text)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		terminates(t, input, func() {
+			l := NewLexer(input)
+			var last Pos
+			for _, i := range l.Items() {
+				if i.pos < last {
+					t.Fatalf("NewLexer(%q) item %q at %d is before previous item at %d.", input, i.val, i.pos, last)
+				}
+				if int(i.pos) > len(l.input) {
+					t.Fatalf("NewLexer(%q) item %q at %d is past end of input.", input, i.val, i.pos)
+				}
+				last = i.pos
+			}
+		})
+	})
+}
+
+func FuzzParseWord(f *testing.F) {
+	for _, tt := range parseTests {
+		f.Add(tt.word, tt.text)
+	}
+	f.Fuzz(func(t *testing.T, title, text string) {
+		terminates(t, text, func() {
+			ParseWord(Page{Title: title, Text: text}, lang.DefaultLangMap)
+		})
+	})
+}
+
+func FuzzParseEtymTree(f *testing.F) {
+	for _, text := range etymTreeSeeds {
+		f.Add(text)
+	}
+	for _, tt := range parseTests {
+		f.Add(tt.text)
+	}
+	f.Fuzz(func(t *testing.T, text string) {
+		terminates(t, text, func() {
+			ParseEtymTree(Page{Title: etymTree + "la/germanus", Text: text}, lang.DefaultLangMap)
+		})
+	})
+}
@@ -14,36 +14,128 @@ import (
 
 const previousDir = "previous"
 
-// ReadGob reads gob either from path or compressed path.
-func ReadGob(path string, data interface{}) error {
-	var f io.ReadCloser
+// Version of gobs written by WriteGob. Bump it when a change to Word or
+// Descendants means older gobs can't be decoded, and register a Migration to
+// the new version.
+const GobVersion = 1
+
+type GobKind string
+
+const (
+	WordsGob       GobKind = "words"       // map[string]*Word
+	DescendantsGob GobKind = "descendants" // map[string]Descendants
+)
+
+// Gobs written by WriteGob start with a header so ReadGob knows which version
+// to migrate from. Gobs written before versioning have no header and are
+// version 0.
+type gobHeader struct {
+	Magic   string
+	Version int
+	Kind    GobKind
+}
+
+const gobMagic = "glossterm"
+
+// gobKind returns the kind of gob for data, or "" if it isn't words or
+// descendants.
+func gobKind(data interface{}) GobKind {
+	switch data.(type) {
+	case map[string]*Word, *map[string]*Word:
+		return WordsGob
+	case map[string]Descendants, *map[string]Descendants:
+		return DescendantsGob
+	}
+	return ""
+}
+
+// openGob opens path, or the compressed path if path doesn't exist.
+func openGob(path string) (io.ReadCloser, error) {
 	if exists(path) {
-		wf, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		f = wf
-	} else {
-		ext := filepath.Ext(path)
-		base := strings.TrimSuffix(path, ext)
-		compressed := fmt.Sprintf("%s.gob.gz", base)
-		log.Printf("Uncompressing %q.", compressed)
-		cf, err := os.Open(compressed)
-		if err != nil {
-			return err
-		}
-		gr, err := gzip.NewReader(cf)
-		if err != nil {
-			return err
-		}
-		f = gr
+		return os.Open(path)
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	compressed := fmt.Sprintf("%s.gob.gz", base)
+	log.Printf("Uncompressing %q.", compressed)
+	cf, err := os.Open(compressed)
+	if err != nil {
+		return nil, err
+	}
+	gr, err := gzip.NewReader(cf)
+	if err != nil {
+		cf.Close()
+		return nil, err
+	}
+	return gzipFile{gr, cf}, nil
+}
+
+// gzipFile closes both the gzip reader and the file.
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g gzipFile) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// openVersionedGob opens the gob at path and returns a decoder positioned
+// after its header, along with the header.
+func openVersionedGob(path string) (io.ReadCloser, *gob.Decoder, gobHeader, error) {
+	f, err := openGob(path)
+	if err != nil {
+		return nil, nil, gobHeader{}, err
+	}
+	dec := gob.NewDecoder(f)
+	var h gobHeader
+	if err := dec.Decode(&h); err == nil && h.Magic == gobMagic {
+		return f, dec, h, nil
 	}
-	err := gob.NewDecoder(f).Decode(data)
+
+	// Unversioned gob, which has to be decoded from the start again.
 	f.Close()
+	f, err = openGob(path)
+	if err != nil {
+		return nil, nil, gobHeader{}, err
+	}
+	return f, gob.NewDecoder(f), gobHeader{}, nil
+}
+
+// GobVersionOf returns the version and kind of the gob at path. The kind
+// isn't known for version 0 gobs.
+func GobVersionOf(path string) (int, GobKind, error) {
+	f, _, h, err := openVersionedGob(path)
+	if err != nil {
+		return 0, "", err
+	}
+	f.Close()
+	return h.Version, h.Kind, nil
+}
+
+// ReadGob reads gob either from path or compressed path, migrating it if it
+// was written at an older version.
+func ReadGob(path string, data interface{}) error {
+	f, dec, h, err := openVersionedGob(path)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer f.Close()
+
+	if h.Version != GobVersion {
+		kind := h.Kind
+		if kind == "" {
+			kind = gobKind(data)
+		}
+		dec, err = migrateGob(dec, h.Version, kind)
+		if err != nil {
+			return fmt.Errorf("unable to migrate %q: %s", path, err)
+		}
+		log.Printf("Migrated %q from version %d to %d.", path, h.Version, GobVersion)
+	}
+
+	return dec.Decode(data)
 }
 
 func backup(p string) error {
@@ -90,6 +182,10 @@ func WriteGob(p string, data interface{}, verbose bool, shouldBackup bool) error
 
 	// Write gob and gzip simultaneously.
 	enc := gob.NewEncoder(w)
+	err = enc.Encode(gobHeader{Magic: gobMagic, Version: GobVersion, Kind: gobKind(data)})
+	if err != nil {
+		return err
+	}
 	err = enc.Encode(data)
 	if err != nil {
 		return err
@@ -0,0 +1,134 @@
+package gt
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/vthommeret/glossterm/lib/lang"
+)
+
+// Run with -update to rewrite the expected output, e.g.
+// go test ./lib/gt -run TestGolden -update
+var update = flag.Bool("update", false, "update golden files")
+
+const goldenDir = "testdata/golden"
+
+// TestGolden parses each page in the golden corpus, which are exported with
+// cmd/gtpage, and compares the output to the expected JSON next to it.
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(goldenDir, "*.xml"))
+	if err != nil {
+		t.Fatalf("Unable to list golden pages: %s", err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("No golden pages found in %s.", goldenDir)
+	}
+
+	for _, path := range paths {
+		p, err := readGoldenPage(path)
+		if err != nil {
+			t.Errorf("Unable to read %s: %s", path, err)
+			continue
+		}
+		if name := PageFilename(p.Title) + ".xml"; filepath.Base(path) != name {
+			t.Errorf("%s: page %q should be in %s.", path, p.Title, name)
+		}
+
+		got, err := goldenJSON(p)
+		if err != nil {
+			t.Errorf("%s: unable to parse %q: %s", path, p.Title, err)
+			continue
+		}
+
+		goldenPath := strings.TrimSuffix(path, ".xml") + ".json"
+		want, err := ioutil.ReadFile(goldenPath)
+		if err != nil && !(*update && os.IsNotExist(err)) {
+			t.Errorf("Unable to read %s: %s", goldenPath, err)
+			continue
+		}
+
+		if bytes.Equal(want, got) {
+			continue
+		}
+
+		diff := goldenDiff(string(want), string(got))
+		if *update {
+			if err := ioutil.WriteFile(goldenPath, got, 0644); err != nil {
+				t.Errorf("Unable to write %s: %s", goldenPath, err)
+				continue
+			}
+			t.Logf("Updated %s:\n%s", goldenPath, diff)
+		} else {
+			t.Errorf("%s: output of %q differs from %s (run with -update to accept):\n%s", path, p.Title, goldenPath, diff)
+		}
+	}
+}
+
+func readGoldenPage(path string) (Page, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Page{}, err
+	}
+	defer f.Close()
+
+	var p Page
+	err = xml.NewDecoder(f).Decode(&p)
+	return p, err
+}
+
+// goldenJSON returns the parsed word, or descendants for etymtree pages.
+func goldenJSON(p Page) ([]byte, error) {
+	var v interface{}
+	if strings.HasPrefix(p.Title, etymTree) {
+		ds, err := ParseEtymTree(p, lang.DefaultLangMap)
+		if err != nil {
+			return nil, err
+		}
+		v = ds
+	} else {
+		w, err := ParseWord(p, lang.DefaultLangMap)
+		if err != nil {
+			return nil, err
+		}
+		v = w
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// goldenDiff returns the changed lines between want and got.
+func goldenDiff(want, got string) string {
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToChars(want, got)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lines)
+
+	var out strings.Builder
+	for _, d := range diffs {
+		var prefix string
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			prefix = "+ "
+		case diffmatchpatch.DiffDelete:
+			prefix = "- "
+		default:
+			continue
+		}
+		for _, line := range strings.SplitAfter(d.Text, "\n") {
+			if line != "" {
+				out.WriteString(prefix + line)
+			}
+		}
+	}
+	return out.String()
+}
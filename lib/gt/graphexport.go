@@ -0,0 +1,272 @@
+package gt
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cayleygraph/cayley/quad"
+	"github.com/cayleygraph/quad/nquads"
+)
+
+// QuadGraph is the etymology graph written by cmd/gtquads, as nodes and edges
+// that can be exported for tools like Gephi and Graphviz.
+type QuadGraph struct {
+	Nodes []QuadNode
+	Edges []QuadEdge
+}
+
+// QuadNode is a word, with an ID of lang/word.
+type QuadNode struct {
+	ID   string `json:"id"`
+	Lang string `json:"lang"`
+	Word string `json:"word"`
+}
+
+// QuadEdge is a quad from Source to Target, e.g. "es/hermano" inherited-from
+// "la/germanus".
+type QuadEdge struct {
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	Predicate string `json:"predicate"`
+}
+
+// NewQuadNode returns the node for an ID of lang/word.
+func NewQuadNode(id string) QuadNode {
+	n := QuadNode{ID: id, Word: id}
+	if i := strings.Index(id, "/"); i >= 0 {
+		n.Lang = id[:i]
+		n.Word = id[i+1:]
+	}
+	return n
+}
+
+// ReadQuadGraph reads a graph from nquads. Nodes are sorted by ID and edges
+// by source, target and predicate.
+func ReadQuadGraph(r io.Reader) (*QuadGraph, error) {
+	nr := nquads.NewReader(r, false)
+
+	var edges []QuadEdge
+	for {
+		q, err := nr.ReadQuad()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("unable to read quad: %s", err)
+		}
+		if !q.IsValid() {
+			continue
+		}
+		edges = append(edges, QuadEdge{
+			Source:    quadString(q.Subject),
+			Target:    quadString(q.Object),
+			Predicate: quadString(q.Predicate),
+		})
+	}
+	return newQuadGraph(edges), nil
+}
+
+// quadString returns the string of a value, which cmd/gtquads writes as
+// literals but could also be IRIs.
+func quadString(v quad.Value) string {
+	switch v := v.(type) {
+	case quad.String:
+		return string(v)
+	case quad.IRI:
+		return string(v)
+	}
+	return v.String()
+}
+
+// newQuadGraph returns the graph of unique edges and the nodes they connect.
+func newQuadGraph(edges []QuadEdge) *QuadGraph {
+	g := &QuadGraph{}
+	seenNodes := map[string]bool{}
+	seenEdges := map[QuadEdge]bool{}
+	for _, e := range edges {
+		if seenEdges[e] {
+			continue
+		}
+		seenEdges[e] = true
+		g.Edges = append(g.Edges, e)
+		for _, id := range []string{e.Source, e.Target} {
+			if !seenNodes[id] {
+				seenNodes[id] = true
+				g.Nodes = append(g.Nodes, NewQuadNode(id))
+			}
+		}
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		return a.Predicate < b.Predicate
+	})
+	return g
+}
+
+// Neighbourhood returns the part of the graph within depth edges of the node
+// with id, following edges in either direction. A negative depth has no
+// limit.
+func (g *QuadGraph) Neighbourhood(id string, depth int) *QuadGraph {
+	adjacent := map[string][]string{}
+	for _, e := range g.Edges {
+		adjacent[e.Source] = append(adjacent[e.Source], e.Target)
+		adjacent[e.Target] = append(adjacent[e.Target], e.Source)
+	}
+
+	within := map[string]bool{id: true}
+	frontier := []string{id}
+	for d := 0; len(frontier) > 0 && (depth < 0 || d < depth); d++ {
+		var next []string
+		for _, n := range frontier {
+			for _, m := range adjacent[n] {
+				if !within[m] {
+					within[m] = true
+					next = append(next, m)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	var edges []QuadEdge
+	for _, e := range g.Edges {
+		if within[e.Source] && within[e.Target] {
+			edges = append(edges, e)
+		}
+	}
+	return newQuadGraph(edges)
+}
+
+// WriteDOT writes the graph in Graphviz DOT format.
+func (g *QuadGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph etymology {\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, lang=%s];\n", dotQuote(n.ID), dotQuote(n.Word+" ("+n.Lang+")"), dotQuote(n.Lang))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s, predicate=%s];\n", dotQuote(e.Source), dotQuote(e.Target), dotQuote(e.Predicate), dotQuote(e.Predicate))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote returns s as a quoted DOT ID.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// GraphML document, see http://graphml.graphdrawing.org/specification.html
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph in GraphML format, with lang and word node
+// attributes and a predicate edge attribute.
+func (g *QuadGraph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "lang", For: "node", AttrName: "lang", AttrType: "string"},
+			{ID: "word", For: "node", AttrName: "word", AttrType: "string"},
+			{ID: "predicate", For: "edge", AttrName: "predicate", AttrType: "string"},
+		},
+	}
+	doc.Graph.EdgeDefault = "directed"
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID:   n.ID,
+			Data: []graphMLData{{Key: "lang", Value: n.Lang}, {Key: "word", Value: n.Word}},
+		})
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.Source,
+			Target: e.Target,
+			Data:   []graphMLData{{Key: "predicate", Value: e.Predicate}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteNodeLink writes the graph as node-link JSON, as read by e.g.
+// networkx.node_link_graph and d3-force.
+func (g *QuadGraph) WriteNodeLink(w io.Writer) error {
+	doc := struct {
+		Directed   bool       `json:"directed"`
+		Multigraph bool       `json:"multigraph"`
+		Nodes      []QuadNode `json:"nodes"`
+		Links      []QuadEdge `json:"links"`
+	}{
+		Directed:   true,
+		Multigraph: true,
+		Nodes:      g.Nodes,
+		Links:      g.Edges,
+	}
+	if doc.Nodes == nil {
+		doc.Nodes = []QuadNode{}
+	}
+	if doc.Links == nil {
+		doc.Links = []QuadEdge{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,119 @@
+package gt
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// As written by cmd/gtquads, plus a duplicate quad.
+const graphQuads = `"es/hermano" "inherited-from" "la/germanus" .
+"la/germanus" "descendant" "es/hermano" .
+"fr/germain" "inherited-from" "la/germanus" .
+"la/germanus" "descendant" "fr/germain" .
+"la/germanus" "descendant" "fr/germain" .
+<fr/germain> <mentions> <la/germen> .
+`
+
+func readTestQuadGraph(t *testing.T) *QuadGraph {
+	g, err := ReadQuadGraph(strings.NewReader(graphQuads))
+	if err != nil {
+		t.Fatalf("gt.ReadQuadGraph(...) got error: %s.", err)
+	}
+	return g
+}
+
+func TestQuadGraphDOT(t *testing.T) {
+	g := readTestQuadGraph(t).Neighbourhood("es/hermano", 1)
+
+	var b bytes.Buffer
+	if err := g.WriteDOT(&b); err != nil {
+		t.Fatalf("QuadGraph.WriteDOT() got error: %s.", err)
+	}
+	want := `digraph etymology {
+  "es/hermano" [label="hermano (es)", lang="es"];
+  "la/germanus" [label="germanus (la)", lang="la"];
+  "es/hermano" -> "la/germanus" [label="inherited-from", predicate="inherited-from"];
+  "la/germanus" -> "es/hermano" [label="descendant", predicate="descendant"];
+}
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("QuadGraph.WriteDOT() diff: %s", diff)
+	}
+}
+
+func TestQuadGraphNeighbourhood(t *testing.T) {
+	g := readTestQuadGraph(t)
+	for _, tt := range []struct {
+		depth int
+		want  []string
+	}{
+		{0, nil},
+		{1, []string{"es/hermano", "la/germanus"}},
+		{2, []string{"es/hermano", "fr/germain", "la/germanus"}},
+		{-1, []string{"es/hermano", "fr/germain", "la/germanus", "la/germen"}},
+	} {
+		var got []string
+		for _, n := range g.Neighbourhood("es/hermano", tt.depth).Nodes {
+			got = append(got, n.ID)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("QuadGraph.Neighbourhood(%q, %d) diff: %s", "es/hermano", tt.depth, diff)
+		}
+	}
+}
+
+func TestQuadGraphGraphML(t *testing.T) {
+	g := readTestQuadGraph(t)
+
+	var b bytes.Buffer
+	if err := g.WriteGraphML(&b); err != nil {
+		t.Fatalf("QuadGraph.WriteGraphML() got error: %s.", err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("Unable to decode GraphML: %s", err)
+	}
+	if got := len(doc.Graph.Nodes); got != 4 {
+		t.Errorf("QuadGraph.WriteGraphML() got %d nodes, want 4.", got)
+	}
+	// The duplicate descendant quad is only written once.
+	if got := len(doc.Graph.Edges); got != 5 {
+		t.Errorf("QuadGraph.WriteGraphML() got %d edges, want 5.", got)
+	}
+	want := graphMLEdge{Source: "es/hermano", Target: "la/germanus", Data: []graphMLData{{Key: "predicate", Value: "inherited-from"}}}
+	if diff := cmp.Diff(want, doc.Graph.Edges[0]); diff != "" {
+		t.Errorf("QuadGraph.WriteGraphML() diff: %s", diff)
+	}
+}
+
+func TestQuadGraphNodeLink(t *testing.T) {
+	g := readTestQuadGraph(t).Neighbourhood("la/germen", 1)
+
+	var b bytes.Buffer
+	if err := g.WriteNodeLink(&b); err != nil {
+		t.Fatalf("QuadGraph.WriteNodeLink() got error: %s.", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("Unable to decode node-link JSON: %s", err)
+	}
+	want := map[string]interface{}{
+		"directed":   true,
+		"multigraph": true,
+		"nodes": []interface{}{
+			map[string]interface{}{"id": "fr/germain", "lang": "fr", "word": "germain"},
+			map[string]interface{}{"id": "la/germen", "lang": "la", "word": "germen"},
+		},
+		"links": []interface{}{
+			map[string]interface{}{"source": "fr/germain", "target": "la/germen", "predicate": "mentions"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("QuadGraph.WriteNodeLink() diff: %s", diff)
+	}
+}
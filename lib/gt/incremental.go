@@ -0,0 +1,203 @@
+package gt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// Adds-changes dumps contain every revision made to pages on a given day.
+// https://dumps.wikimedia.org/other/incr/enwiktionary/
+const incrementalDumpBase = "https://dumps.wikimedia.org/other/incr/enwiktionary"
+
+const incrementalDateFormat = "20060102"
+
+// IncrementalDumpURL returns the URL of the adds-changes dump for a day.
+func IncrementalDumpURL(date time.Time) string {
+	d := date.Format(incrementalDateFormat)
+	return fmt.Sprintf("%s/%s/enwiktionary-%s-pages-meta-hist-incr.xml.bz2", incrementalDumpBase, d, d)
+}
+
+// IncrementalStatusURL returns the URL of the status of the adds-changes dump
+// for a day, which is "done" once the dump is complete.
+func IncrementalStatusURL(date time.Time) string {
+	return fmt.Sprintf("%s/%s/status.txt", incrementalDumpBase, date.Format(incrementalDateFormat))
+}
+
+// Changes are the pages parsed from an adds-changes dump. A page that no
+// longer has any words, or an etymtree without descendants, is nil so it's
+// removed when applied.
+type Changes struct {
+	Words       map[string]*Word
+	Descendants map[string]*Descendants
+}
+
+// ChangeStats counts how words and descendants were changed.
+type ChangeStats struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+}
+
+// ParseChanges parses the pages in an adds-changes dump. Pages can appear more
+// than once, in which case the last revision is used. Errors parsing a page
+// are passed to onError and the page is skipped.
+func ParseChanges(r io.Reader, opts ParseOptions, onError func(Error)) (Changes, error) {
+	c := Changes{
+		Words:       map[string]*Word{},
+		Descendants: map[string]*Descendants{},
+	}
+
+	d := xml.NewDecoder(r)
+	for {
+		t, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return c, fmt.Errorf("unable to decode token: %s", err)
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok || se.Name.Local != "page" {
+			continue
+		}
+		var p Page
+		if err := d.DecodeElement(&p, &se); err != nil {
+			return c, fmt.Errorf("unable to decode page: %s", err)
+		}
+
+		// Exclude namespaced pages, like ParseXMLPages.
+		if strings.Contains(p.Title, ":") && !strings.HasPrefix(p.Title, etymTree) {
+			continue
+		}
+
+		w, ds, err := parsePage(p, opts.Diagnostics)
+		if err != nil {
+			onError(pageError(p.Title, err))
+			continue
+		}
+		if ds != nil {
+			if len(ds.Links) == 0 && len(ds.Descendants) == 0 {
+				ds = nil
+			}
+			c.Descendants[p.Title] = ds
+		} else if w != nil && !w.IsEmpty() {
+			c.Words[p.Title] = w
+		} else {
+			c.Words[p.Title] = nil
+		}
+	}
+
+	return c, nil
+}
+
+// Apply patches words and descendants with the changes. Words are compared
+// as parsed, since words.gob also has what cmd/gtresolve and cmd/gtcognates
+// add, and words that parse the same are kept as is. Changed words have their
+// desctrees (up to maxDepth levels) and etymtrees resolved again and keep
+// their cognates until cmd/gtcognates is run. They aren't marked as indexed
+// so cmd/gtindex only pushes them. Words referring to a changed or removed
+// etymtree are updated with its new descendants.
+func (c Changes) Apply(words map[string]*Word, descendants map[string]Descendants, maxDepth int) (wordStats, descendantStats ChangeStats) {
+	// Etymtrees as they were before changing, to remove what they added.
+	changedTrees := map[string]Descendants{}
+	for name, ds := range c.Descendants {
+		previous, ok := descendants[name]
+		switch {
+		case ds == nil && ok:
+			delete(descendants, name)
+			descendantStats.Removed++
+		case ds == nil:
+			continue
+		case !ok:
+			descendants[name] = *ds
+			descendantStats.Added++
+		case cmp.Equal(previous, *ds):
+			descendantStats.Unchanged++
+			continue
+		default:
+			descendants[name] = *ds
+			descendantStats.Updated++
+		}
+		changedTrees[name] = previous
+	}
+
+	updated := map[string]bool{}
+	for name, w := range c.Words {
+		previous, ok := words[name]
+		switch {
+		case w == nil && ok:
+			delete(words, name)
+			wordStats.Removed++
+		case w == nil:
+		case !ok:
+			words[name] = w
+			updated[name] = true
+			wordStats.Added++
+		case sameParse(previous, w):
+			wordStats.Unchanged++
+		default:
+			for code, l := range w.Languages {
+				if pl, ok := previous.Languages[code]; ok {
+					l.Cognates = pl.Cognates
+				}
+			}
+			words[name] = w
+			updated[name] = true
+			wordStats.Updated++
+		}
+	}
+
+	resolveDescTrees(words, updated, maxDepth)
+
+	changedTreeNames := map[string]bool{}
+	for name := range changedTrees {
+		changedTreeNames[name] = true
+	}
+	for name, w := range words {
+		if updated[name] {
+			for _, l := range w.Languages {
+				l.addEtymTrees(descendants, nil)
+			}
+			continue
+		}
+		changed := false
+		for _, l := range w.Languages {
+			for _, t := range l.DescendantTrees {
+				if previous, ok := changedTrees[t.ToEntryName()]; ok {
+					l.removeEtymTree(previous)
+					changed = true
+				}
+			}
+			l.addEtymTrees(descendants, changedTreeNames)
+		}
+		if changed {
+			if _, ok := c.Words[name]; ok {
+				wordStats.Unchanged--
+			}
+			w.Indexed = nil
+			wordStats.Updated++
+		}
+	}
+
+	return wordStats, descendantStats
+}
+
+// sameParse reports whether a word parses the same as it did before. Words
+// written before digests were kept are compared without the fields that are
+// added to after parsing.
+func sameParse(previous, w *Word) bool {
+	if previous.Digest != "" {
+		return previous.Digest == w.Digest
+	}
+	return cmp.Equal(previous, w,
+		cmpopts.IgnoreUnexported(Language{}),
+		cmpopts.IgnoreFields(Word{}, "Indexed", "Digest"),
+		cmpopts.IgnoreFields(Language{}, "Links", "Descendants", "DescendantTree", "Cognates"))
+}
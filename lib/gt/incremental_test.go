@@ -0,0 +1,191 @@
+package gt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vthommeret/glossterm/lib/lang"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+const changesDump = `<mediawiki>
+  <page>
+    <title>hombre</title>
+    <ns>0</ns>
+    <revision><text>==Spanish==
+===Noun===
+# [[male]]</text></revision>
+    <revision><text>==Spanish==
+===Noun===
+# [[man]]</text></revision>
+  </page>
+  <page>
+    <title>libro</title>
+    <ns>0</ns>
+    <revision><text>==Spanish==
+===Noun===
+# [[book]]</text></revision>
+  </page>
+  <page>
+    <title>nariz</title>
+    <ns>0</ns>
+    <revision><text>{{delete}}</text></revision>
+  </page>
+  <page>
+    <title>casa</title>
+    <ns>0</ns>
+    <revision><text>==Spanish==
+===Noun===
+# [[house]]</text></revision>
+  </page>
+  <page>
+    <title>gato</title>
+    <ns>0</ns>
+    <revision><text>==Spanish==
+===Noun===
+# [[cat]]</text></revision>
+  </page>
+  <page>
+    <title>User:Someone</title>
+    <ns>2</ns>
+    <revision><text>==Spanish==
+===Noun===
+# [[user]]</text></revision>
+  </page>
+</mediawiki>`
+
+func TestChanges(t *testing.T) {
+	c, err := ParseChanges(strings.NewReader(changesDump), ParseOptions{}, func(e Error) {
+		t.Errorf("gt.ParseChanges(...) got error: %s", e)
+	})
+	if err != nil {
+		t.Fatalf("gt.ParseChanges(...) got error: %s", err)
+	}
+
+	indexed := time.Now()
+	cognates := []*Cognate{{Word: "pt/casa", From: "la/casa"}}
+	words := map[string]*Word{}
+	for title, text := range map[string]string{
+		"hombre": "==Spanish==\n===Noun===\n# [[male]]",
+		"nariz":  "==Spanish==\n===Noun===\n# [[nose]]",
+		"casa":   "==Spanish==\n===Noun===\n# [[house]]",
+		"gato":   "==Spanish==\n===Noun===\n# [[cat]]",
+	} {
+		w, _, err := parsePage(Page{Title: title, Text: text}, nil)
+		if err != nil {
+			t.Fatalf("gt.parsePage(%q) got error: %s", title, err)
+		}
+		// Added to by cmd/gtcognates and cmd/gtindex.
+		w.Indexed = &indexed
+		w.Languages["es"].Cognates = cognates
+		words[title] = w
+	}
+	// Words from before digests were kept are compared as parsed.
+	words["gato"].Digest = ""
+	words["gato"].Languages["es"].Descendants = []tpl.Descendant{{Lang: "en", Word: "gato"}}
+
+	stats, _ := c.Apply(words, map[string]Descendants{}, DefaultDescTreeDepth)
+
+	want := ChangeStats{Added: 1, Updated: 1, Removed: 1, Unchanged: 2}
+	if stats != want {
+		t.Errorf("Changes.Apply(...) got %+v, want %+v.", stats, want)
+	}
+	if _, ok := words["User:Someone"]; ok {
+		t.Errorf("Changes.Apply(...) added namespaced page.")
+	}
+	if _, ok := words["nariz"]; ok {
+		t.Errorf("Changes.Apply(...) didn't remove %q.", "nariz")
+	}
+	if w := words["hombre"]; w.Indexed != nil || w.Languages["es"].Definitions.Nouns[0].Text != "man" {
+		t.Errorf("Changes.Apply(...) didn't update %q from the last revision.", "hombre")
+	}
+	for _, name := range []string{"hombre", "casa", "gato"} {
+		if diff := cmp.Diff(cognates, words[name].Languages["es"].Cognates); diff != "" {
+			t.Errorf("Changes.Apply(...) %q cognates diff: %s", name, diff)
+		}
+	}
+	for _, name := range []string{"casa", "gato"} {
+		if w := words[name]; w.Indexed == nil {
+			t.Errorf("Changes.Apply(...) marked unchanged %q as changed.", name)
+		}
+	}
+	if w := words["libro"]; w == nil || w.Indexed != nil {
+		t.Errorf("Changes.Apply(...) didn't add %q.", "libro")
+	}
+}
+
+const etymTreeChangesDump = `<mediawiki>
+  <page>
+    <title>Template:etymtree/la/germanus</title>
+    <ns>10</ns>
+    <revision><text>* Spanish: {{l|es|hermano}}
+* Portuguese: {{l|pt|irmão}}</text></revision>
+  </page>
+  <page>
+    <title>Template:etymtree/la/frater</title>
+    <ns>10</ns>
+    <revision><text>{{delete}}</text></revision>
+  </page>
+</mediawiki>`
+
+func TestChangesEtymTrees(t *testing.T) {
+	c, err := ParseChanges(strings.NewReader(etymTreeChangesDump), ParseOptions{}, func(e Error) {
+		t.Errorf("gt.ParseChanges(...) got error: %s", e)
+	})
+	if err != nil {
+		t.Fatalf("gt.ParseChanges(...) got error: %s", err)
+	}
+
+	descendants := map[string]Descendants{}
+	for title, text := range map[string]string{
+		"Template:etymtree/la/germanus": "* Spanish: {{l|es|hermano}}",
+		"Template:etymtree/la/frater":   "* French: {{l|fr|frère}}",
+	} {
+		ds, err := ParseEtymTree(Page{Title: title, Text: text}, lang.DefaultLangMap)
+		if err != nil {
+			t.Fatalf("gt.ParseEtymTree(%q) got error: %s", title, err)
+		}
+		descendants[title] = *ds
+	}
+
+	indexed := time.Now()
+	words := map[string]*Word{}
+	for _, title := range []string{"germanus", "frater"} {
+		text := "==Latin==\n===Noun===\n# [[brother]]\n====Descendants====\n{{etymtree|la||" + title + "}}"
+		w, _, err := parsePage(Page{Title: title, Text: text}, nil)
+		if err != nil {
+			t.Fatalf("gt.parsePage(%q) got error: %s", title, err)
+		}
+		w.Indexed = &indexed
+		words[title] = w
+	}
+	ResolveEtymTrees(words, descendants)
+
+	wordStats, descendantStats := c.Apply(words, descendants, DefaultDescTreeDepth)
+
+	if want := (ChangeStats{Updated: 2}); wordStats != want {
+		t.Errorf("Changes.Apply(...) got word stats %+v, want %+v.", wordStats, want)
+	}
+	if want := (ChangeStats{Updated: 1, Removed: 1}); descendantStats != want {
+		t.Errorf("Changes.Apply(...) got descendant stats %+v, want %+v.", descendantStats, want)
+	}
+
+	tests := []struct {
+		word  string
+		links []tpl.Link
+	}{
+		{"germanus", []tpl.Link{{Lang: "es", Word: "hermano"}, {Lang: "pt", Word: "irmão"}}},
+		{"frater", nil},
+	}
+	for _, tt := range tests {
+		w := words[tt.word]
+		if w.Indexed != nil {
+			t.Errorf("Changes.Apply(...) didn't mark %q as changed.", tt.word)
+		}
+		if diff := cmp.Diff(tt.links, w.Languages["la"].Links); diff != "" {
+			t.Errorf("Changes.Apply(...) %q links diff: %s", tt.word, diff)
+		}
+	}
+}
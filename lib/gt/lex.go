@@ -2,15 +2,15 @@ package gt
 
 import (
 	"fmt"
+	"runtime/debug"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
 // Adapted from https://github.com/golang/go/blob/master/src/text/template/parse/lex.go
 // Also see https://www.youtube.com/watch?v=HxaD_trXwRE
 
-// TODO: Can HasPrefix use be minimized vs. using peek/accept from original lex.go?
-
 // Pos represents a byte position in the original input text from which
 // this template was parsed.
 type Pos int
@@ -22,7 +22,7 @@ type item struct {
 	typ      itemType // The type of this item.
 	pos      Pos      // The starting position, in bytes, of this item in the input string.
 	val      string   // The value of this item.
-	depth    int      // Used by itemHeaderStart and itemHeaderEnd to indicate header depth.
+	depth    int      // Used by itemHeaderStart, itemHeaderEnd and list items to indicate depth.
 	balanced bool     // Used for itemLeftTemplate to indicate whether it has a matching itemRightTemplate.
 }
 
@@ -42,12 +42,11 @@ func (i item) String() string {
 type itemType int
 
 const (
-	itemError itemType = iota // error occurred; value is text of error
+	itemError itemType = iota // lexer panicked; value is text of error
 	itemEOF
 	itemAction      // Template action
 	itemParamDelim  // Template parameter delimiter
 	itemParamName   // Template parameter name
-	itemParamText   // Template parameter text
 	itemHeaderStart // Header start
 	itemHeaderEnd   // Header end
 
@@ -84,6 +83,13 @@ const (
 	itemTagCommentLeft  // HTML comment left delimiter
 	itemTagComment      // HTML comment
 	itemTagCommentRight // HTML comment left delimiter
+
+	itemTableStart      // Table start
+	itemTableEnd        // Table end
+	itemTableRow        // Table row delimiter
+	itemTableCaption    // Table caption delimiter
+	itemTableCell       // Table cell delimiter
+	itemTableHeaderCell // Table header cell delimiter
 )
 
 // Make the types prettyprint.
@@ -93,7 +99,6 @@ var itemName = map[itemType]string{
 	itemAction:      "action",
 	itemParamDelim:  "param delim",
 	itemParamName:   "param name",
-	itemParamText:   "param text",
 	itemHeaderStart: "header start",
 	itemHeaderEnd:   "header end",
 
@@ -130,33 +135,27 @@ var itemName = map[itemType]string{
 	itemTagCommentLeft:  "tag comment left",
 	itemTagComment:      "tag comment",
 	itemTagCommentRight: "tag comment right",
+
+	itemTableStart:      "table start",
+	itemTableEnd:        "table end",
+	itemTableRow:        "table row",
+	itemTableCaption:    "table caption",
+	itemTableCell:       "table cell",
+	itemTableHeaderCell: "table header cell",
 }
 
-// From http://w3c.github.io/html/syntax.html#void-elements
-var voidTags = []string{
-	"area",
-	"base",
-	"br",
-	"col",
-	"embed",
-	"hr",
-	"img",
-	"input",
-	"link",
-	"menuitem",
-	"meta",
-	"param",
-	"source",
-	"track",
-	"wbr",
-}
-var voidTagMap = map[string]bool{}
+// Tags whose contents aren't wikitext, e.g. <nowiki>[[not a link]]</nowiki>.
+var rawTags = map[string]bool{
+	"nowiki": true,
+	"pre":    true,
+	"math":   true,
+}
 
+// buffer holds items emitted within templates until the outermost template
+// is closed, in case an unclosed template needs to be turned back into text.
 type buffer struct {
-	buffering bool
-	items     []*item // items buffered
-	tpls      []*item // pointers to template opens
-	openTpls  stack   // stack of open template indices
+	items    []*item // items buffered
+	openTpls stack   // stack of open template indices
 }
 
 func (i itemType) String() string {
@@ -172,18 +171,45 @@ const eof = -1
 // stateFn represents the state of the scanner as a function that returns the next state.
 type stateFn func(*lexer) stateFn
 
+// scopeType identifies a construct that can contain other constructs.
+type scopeType int
+
+const (
+	templateScope scopeType = iota
+	linkScope
+)
+
+// scope is an open template or link.
+type scope struct {
+	typ   scopeType
+	named bool // Used by templateScope to indicate the current parameter is named.
+}
+
 // lexer holds the state of the scanner.
 type lexer struct {
-	input      string    // the string being scanned
-	state      stateFn   // the next lexing function to enter
-	pos        Pos       // current position in the input
-	start      Pos       // start position of this item
-	width      Pos       // width of last rune read from input
-	items      chan item // channel of scanned items
-	buffered   buffer    // buffer of items before they're emitted
-	tplDepth   int       // template depth
-	inListItem bool
-	debug      bool // output debug messages automatically
+	input        string    // the string being scanned
+	state        stateFn   // the next lexing function to enter
+	pos          Pos       // current position in the input
+	start        Pos       // start position of this item
+	width        Pos       // width of last rune read from input
+	items        chan item // channel of scanned items
+	buffered     buffer    // buffer of items within templates
+	scopes       []scope   // stack of open templates and links
+	tableDepth   int       // table depth
+	inHeader     bool      // whether a header start has been emitted on this line
+	inHeaderCell bool      // whether the current table line started with a header cell
+	inListItem   bool      // whether a list item start has been emitted
+	tagName      string    // name of the last HTML tag
+	closingTag   bool      // whether the last HTML tag is a closing tag
+	quote        rune      // quote of the current HTML attribute value, if any
+	debug        bool      // output debug messages automatically
+	offset       Pos       // offset of the input within the page text
+	lineOffset   int       // lines in the page text before the input
+	panicStack   string    // stack trace if the lexer panicked
+	panicItem    *item     // error returned once items is closed if the lexer panicked
+
+	// done is closed by Stop once the parser stops reading items.
+	done chan struct{}
 }
 
 // next returns the next rune in the input.
@@ -218,7 +244,7 @@ func (l *lexer) backup() {
 	}
 }
 
-// advanced steps forward one rune. Can only be called once per call of next.
+// advance steps forward one rune. Can only be called once per call of backup.
 func (l *lexer) advance() {
 	l.pos += l.width
 	if l.debug {
@@ -226,23 +252,44 @@ func (l *lexer) advance() {
 	}
 }
 
+// remaining returns the input that hasn't been scanned yet.
+func (l *lexer) remaining() string {
+	return l.input[l.pos:]
+}
+
+// atLineStart reports whether the scanner is at the start of a line.
+func (l *lexer) atLineStart() bool {
+	return l.pos == 0 || l.input[l.pos-1] == '\n'
+}
+
 // emit passes an item back to the client.
 func (l *lexer) emit(t itemType) {
-	l.emitItem(t, false)
+	l.emitItem(t, 0, false)
 }
 
 // emitTrim passes a trimmed item back to the client.
 func (l *lexer) emitTrim(t itemType) {
-	l.emitItem(t, true)
+	l.emitItem(t, 0, true)
+}
+
+// emitDepth passes a item with depth back to the client.
+func (l *lexer) emitDepth(t itemType, depth int) {
+	l.emitItem(t, depth, false)
+}
+
+// emitAnyText passes pending input back to the client as text.
+func (l *lexer) emitAnyText() {
+	if l.pos > l.start {
+		l.emit(itemText)
+	}
 }
 
 // emitItem passes an item back to the client.
-func (l *lexer) emitItem(t itemType, trim bool) {
+func (l *lexer) emitItem(t itemType, depth int, trim bool) {
 	input := l.input[l.start:l.pos]
 	if trim {
 		input = strings.TrimSpace(input)
 	}
-	it := item{t, l.start, input, 0, false}
 	if l.debug {
 		var val string
 		if l.pos > l.start {
@@ -250,36 +297,31 @@ func (l *lexer) emitItem(t itemType, trim bool) {
 		}
 		l.printDebug("emit", fmt.Sprintf("%s%s", t.String(), val))
 	}
-	if l.buffered.buffering {
-		l.buffer(t)
-	} else {
-		l.items <- it
-		l.start = l.pos
-	}
+	l.send(item{t, l.start, input, depth, false})
+	l.start = l.pos
 }
 
-// buffer an item for emit, in case we need to backtrack.
-func (l *lexer) buffer(t itemType) {
-	i := item{t, l.start, l.input[l.start:l.pos], 0, false}
+// send passes an item back to the client, buffering it while within a
+// template in case the template is never closed.
+func (l *lexer) send(i item) {
+	if len(l.buffered.openTpls) == 0 && i.typ != itemLeftTemplate {
+		l.deliver(i)
+		return
+	}
 	l.buffered.items = append(l.buffered.items, &i)
-	switch t {
+	switch i.typ {
 	case itemLeftTemplate:
-		l.buffered.tpls = append(l.buffered.tpls, &i)
-		l.buffered.openTpls.push(len(l.buffered.tpls) - 1)
+		l.buffered.openTpls.push(len(l.buffered.items) - 1)
 	case itemRightTemplate:
 		lastOpen := l.buffered.openTpls.pop()
-		l.buffered.tpls[lastOpen].balanced = true
+		l.buffered.items[lastOpen].balanced = true
+		if len(l.buffered.openTpls) == 0 {
+			for _, i := range l.buffered.items {
+				l.deliver(*i)
+			}
+			l.buffered = buffer{}
+		}
 	}
-	l.start = l.pos
-}
-
-// emitDepth passes a item with depth back to the client.
-func (l *lexer) emitDepth(t itemType, depth int) {
-	if l.debug {
-		l.printDebug("emit", fmt.Sprintf("%s → %s", t.String(), l.input[l.start:l.pos]))
-	}
-	l.items <- item{t, l.start, l.input[l.start:l.pos], depth, false}
-	l.start = l.pos
 }
 
 // ignore skips over the pending input before this point.
@@ -290,91 +332,125 @@ func (l *lexer) ignore() {
 	}
 }
 
-// accept consumes the next rune if it's from the valid set.
-func (l *lexer) accept(valid string) bool {
-	if strings.ContainsRune(valid, l.next()) {
-		return true
+// pushScope opens a template or link.
+func (l *lexer) pushScope(t scopeType) {
+	l.scopes = append(l.scopes, scope{typ: t})
+}
+
+// popScope closes the innermost scope of the given type along with any
+// scopes left open within it.
+func (l *lexer) popScope(t scopeType) {
+	for i := len(l.scopes) - 1; i >= 0; i-- {
+		if l.scopes[i].typ == t {
+			l.scopes = l.scopes[:i]
+			return
+		}
 	}
-	l.backup()
-	return false
 }
 
-// acceptRun consumes a run of runes from the valid set.
-func (l *lexer) acceptRun(valid string) {
-	for strings.ContainsRune(valid, l.next()) {
+// innermost returns the innermost open scope, or nil.
+func (l *lexer) innermost() *scope {
+	if len(l.scopes) == 0 {
+		return nil
 	}
-	l.backup()
+	return &l.scopes[len(l.scopes)-1]
 }
 
-// errorf returns an error token and terminates the scan by passing
-// back a nil pointer that will be the next state, terminating l.nextItem.
-func (l *lexer) errorf(format string, args ...interface{}) stateFn {
-	l.items <- item{itemError, l.start, fmt.Sprintf(format, args...), 0, false}
-	return nil
+// inScope reports whether the innermost open scope is of the given type.
+func (l *lexer) inScope(t scopeType) bool {
+	s := l.innermost()
+	return s != nil && s.typ == t
+}
+
+// inTemplate reports whether any template is open.
+func (l *lexer) inTemplate() bool {
+	return len(l.buffered.openTpls) > 0
+}
+
+// endListItem ends the current list item, if any.
+func (l *lexer) endListItem() {
+	if l.inListItem {
+		l.emitAnyText()
+		l.inListItem = false
+		l.emit(itemListItemEnd)
+	}
 }
 
-func (l *lexer) lexListItem(startDelim string, startItem itemType, endItem itemType, definitionItem itemType, alternateDelim string, alternateItem itemType) (bool, stateFn) {
+func (l *lexer) lexListItem(startDelim string, startItem itemType, definitionItem itemType, alternateDelim string, alternateItem itemType) stateFn {
+	remaining := l.remaining()
 	for i := listItems; i > 0; i-- {
 		delim := strings.Repeat(startDelim, i)
 
+		var it itemType
+		var prefix string
+
+		switch {
 		// Definition list item (#:)
-		defnPrefix := fmt.Sprintf("%s%s", delim, definitionStart)
-		if strings.HasPrefix(l.input[l.pos:], defnPrefix) {
-			if l.inListItem {
-				l.emit(endItem)
-			}
-			l.inListItem = true
-			return true, lexDelim(definitionItem, defnPrefix, i)
-		}
+		case strings.HasPrefix(remaining, delim+definitionStart):
+			it, prefix = definitionItem, delim+definitionStart
 
 		// For ordered list within unordered list, or visa versa...
 		// From "nonhospital":
 		// # An [[institution]] that is not a hospital.
 		// #* '''1988''', LaVonne Straub, ‎Norman Walzer, ''Financing Rural Health Care'' (page 97)
-		alternatePrefix := fmt.Sprintf("%s%s", delim, alternateDelim)
-		if strings.HasPrefix(l.input[l.pos:], alternatePrefix) {
-			if l.inListItem {
-				l.emit(endItem)
-			}
-			l.inListItem = true
-			return true, lexDelim(alternateItem, alternatePrefix, i)
-		}
+		case strings.HasPrefix(remaining, delim+alternateDelim):
+			it, prefix = alternateItem, delim+alternateDelim
 
 		// Regular list item
-		prefix := fmt.Sprintf("%s", delim)
-		if strings.HasPrefix(l.input[l.pos:], prefix) {
-			if l.inListItem {
-				l.emit(endItem)
-			} else {
-				l.inListItem = true
-			}
-			if l.pos > l.start {
-				l.emit(itemText)
-			}
-			l.ignore()
-			return true, lexDelim(startItem, prefix, i)
+		case strings.HasPrefix(remaining, delim):
+			it, prefix = startItem, delim
+
+		default:
+			continue
 		}
+
+		l.endListItem()
+		l.emitAnyText()
+		l.inListItem = true
+		return lexDelim(it, prefix, i)
 	}
-	return false, nil
+	return nil
 }
 
 // NextItem returns the next item from the input.
 // Called by the parser, not in the lexing goroutine.
 func (l *lexer) NextItem() item {
-	item := <-l.items
+	item, ok := <-l.items
+	if !ok && l.panicItem != nil {
+		return *l.panicItem
+	}
 	return item
 }
 
 // NewLexer creates a new scanner for the input string.
 func NewLexer(input string) *lexer {
+	trimmed := strings.TrimLeftFunc(input, unicode.IsSpace)
+	leading := input[:len(input)-len(trimmed)]
+	l := newLexer(strings.TrimRightFunc(trimmed, unicode.IsSpace), false)
+	l.offset = Pos(len(leading))
+	l.lineOffset = strings.Count(leading, "\n")
+	return l
+}
+
+func newLexer(input string, debug bool) *lexer {
 	l := &lexer{
-		input: strings.TrimSpace(input),
+		input: input,
 		items: make(chan item),
+		done:  make(chan struct{}),
+		debug: debug,
 	}
 	go l.run()
 	return l
 }
 
+// position returns the byte offset and line of pos within the page text.
+func (l *lexer) position(pos Pos) (offset int, line int) {
+	if int(pos) > len(l.input) {
+		pos = Pos(len(l.input))
+	}
+	return int(l.offset + pos), 1 + l.lineOffset + strings.Count(l.input[:pos], "\n")
+}
+
 func (l *lexer) printDebug(fn, val string) {
 	var leftCursor, inner, rightCursor string
 	width := l.pos - l.start
@@ -407,7 +483,7 @@ func (l *lexer) String(items []item) string {
 			}
 			break
 		}
-		if i.typ == itemHeaderStart || i.typ == itemHeaderEnd || i.typ == itemUnorderedListItemStart || i.typ == itemOrderedListItemStart || i.typ == itemOrderedDefinitionStart || i.typ == itemUnorderedDefinitionStart {
+		if i.typ == itemHeaderStart || i.typ == itemHeaderEnd || i.typ == itemUnorderedListItemStart || i.typ == itemOrderedListItemStart || i.typ == itemOrderedDefinitionStart || i.typ == itemUnorderedDefinitionStart || i.typ == itemOrderedUnorderedStart || i.typ == itemUnorderedOrderedStart {
 			fmt.Fprintf(&b, "%s, %d: %q\n", i.typ, i.depth, i.val)
 		} else {
 			fmt.Fprintf(&b, "%s: %q\n", i.typ, i.val)
@@ -416,6 +492,7 @@ func (l *lexer) String(items []item) string {
 	return b.String()
 }
 
+// Items returns all items up to EOF or the first error.
 func (l *lexer) Items() []item {
 	var items []item
 	for {
@@ -424,66 +501,107 @@ func (l *lexer) Items() []item {
 			break
 		}
 		items = append(items, i)
+		if i.typ == itemError {
+			break
+		}
 	}
 	return items
 }
 
 // run runs the state machine for the lexer.
+//
+// General flow is characters within template parameter values, links, HTML tags
+// and markup are all considered text and are processed by "lexText". This allows
+// arbitrary nesting of templates, HTML tags, markup within text. HTML attributes,
+// HTML comments, template action names and link targets have their own parsers
+// and don't support nesting (e.g. evaluating a template within an HTML attribute
+// value)
 func (l *lexer) run() {
-	for l.state = lexText; l.state != nil; {
+	defer func() {
+		if r := recover(); r != nil {
+			// The parser may have stopped reading, so the error is
+			// returned by NextItem once items is closed rather than sent.
+			l.panicStack = string(debug.Stack())
+			l.panicItem = &item{itemError, l.pos, fmt.Sprintf("panic: %v", r), 0, false}
+			close(l.items)
+		}
+	}()
+	if l.debug {
+		l.printDebug("start", "")
+	}
+	for l.state = lexText; l.state != nil && !l.stopped(); {
 		l.state = l.state(l)
 	}
 	close(l.items)
 }
 
-// drainTplBuffer drains the tpl buffer
+// deliver passes an item to the parser, dropping it once the parser has
+// stopped reading.
+func (l *lexer) deliver(i item) {
+	select {
+	case l.items <- i:
+	case <-l.done:
+	}
+}
+
+// Stop stops the lexing goroutine, for parsers that return, or panic, before
+// reading up to EOF. Called by the parser, not in the lexing goroutine.
+func (l *lexer) Stop() {
+	if !l.stopped() {
+		close(l.done)
+	}
+}
+
+// stopped reports whether Stop was called.
+func (l *lexer) stopped() bool {
+	select {
+	case <-l.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// drainTplBuffer drains the tpl buffer when the templates within it can't be
+// closed. Balanced templates are passed back as is, anything else is merged
+// back into text.
 func (l *lexer) drainTplBuffer() {
-	s := item{typ: itemText}
+	items := l.buffered.items
+	l.buffered = buffer{}
+
+	var s *item
 	openTpls := stack{}
-	for n, i := range l.buffered.items {
+	for n, i := range items {
 		if i.typ == itemLeftTemplate {
 			openTpls.push(n)
 		}
 		tpl := openTpls.peek()
-		if tpl != -1 && l.buffered.items[tpl].balanced {
-			if s.val != "" {
-				l.items <- s
-				s = item{typ: itemText}
+		if tpl != -1 && items[tpl].balanced {
+			if s != nil {
+				s.val = l.input[s.pos:i.pos]
+				l.deliver(*s)
+				s = nil
 			}
-			l.items <- *i
-		} else {
-			if s.val == "" {
-				s.pos = i.pos
-			}
-			s.val += i.val
+			l.deliver(*i)
+		} else if s == nil {
+			s = &item{typ: itemText, pos: i.pos}
 		}
 		if i.typ == itemRightTemplate {
 			openTpls.pop()
 		}
 	}
-	if s.val != "" {
-		l.items <- s
+	if s != nil {
+		s.val = l.input[s.pos:l.start]
+		l.deliver(*s)
 	}
-	l.buffered = buffer{}
-}
 
-// drainLinkBuffer drains the link buffer
-func (l *lexer) drainLinkBuffer(asText bool) {
-	s := item{typ: itemText}
-	for _, i := range l.buffered.items {
-		if asText {
-			if s.val == "" {
-				s.pos = i.pos
-			}
-			s.val += i.val
-		} else {
-			l.items <- *i
+	// Anything opened within the unclosed templates is abandoned.
+	for i, s := range l.scopes {
+		if s.typ == templateScope {
+			l.scopes = l.scopes[:i]
+			break
 		}
 	}
-	if s.val != "" {
-		l.items <- s
-	}
-	l.buffered = buffer{}
 }
 
 // state functions
@@ -495,10 +613,8 @@ const (
 	orderedListItemStart   = "#"
 	unorderedListItemStart = "*"
 	definitionStart        = ":"
-	listItemEndDelim       = ": "
 	leftTemplate           = "{{"
 	rightTemplate          = "}}"
-	paramEqual             = "="
 	paramDelim             = "|"
 	leftLink               = "[["
 	rightLink              = "]]"
@@ -509,124 +625,229 @@ const (
 	strong           = "'''"
 	emphasized       = "''"
 
-	openTagLeft        = "<"
-	closeTagLeft       = "</"
-	closeTagRight      = "/>"
-	tagRight           = ">"
-	tagAttrEqual       = "="
-	tagAttrSingleQuote = "'"
-	tagAttrDoubleQuote = "\""
-	tagCommentLeft     = "<!--"
-	tagCommentRight    = "-->"
+	openTagLeft     = "<"
+	closeTagLeft    = "</"
+	closeTagRight   = "/>"
+	tagRight        = ">"
+	tagCommentLeft  = "<!--"
+	tagCommentRight = "-->"
+
+	tableStart            = "{|"
+	tableEnd              = "|}"
+	tableRow              = "|-"
+	tableCaption          = "|+"
+	tableCell             = "|"
+	tableHeaderCell       = "!"
+	tableInlineCell       = "||"
+	tableInlineHeaderCell = "!!"
 )
 
-// lexText scans until a header or template delimiter.
+// lexText scans text until the start of another construct.
 func lexText(l *lexer) stateFn {
 Loop:
 	for {
-		startOfLine := true
-		if l.pos > 0 {
-			startOfLine = (l.input[l.pos-1:l.pos] == "\n")
+		if l.atLineStart() {
+			if next := lexLineStart(l); next != nil {
+				return next
+			}
 		}
 
-		if startOfLine {
-			// Headers
+		remaining := l.remaining()
+
+		// Lex header ends
+		if l.inHeader && len(l.scopes) == 0 {
+			line := remaining
+			if i := strings.IndexByte(line, '\n'); i != -1 {
+				line = line[:i]
+			}
+			line = strings.TrimRight(line, " \t\r")
 			for i := headers; i > 0; i-- {
 				delim := strings.Repeat(headerDelim, i)
-				if strings.HasPrefix(l.input[l.pos:], delim) {
-					if l.inListItem {
-						l.inListItem = false
-						l.emit(itemListItemEnd)
-					}
-					if l.pos > l.start {
-						l.emit(itemText)
-					}
-					l.ignore()
-					return lexDelim(itemHeaderStart, delim, i)
+				if line == delim {
+					l.emitAnyText()
+					l.inHeader = false
+					return lexDelim(itemHeaderEnd, delim, i)
 				}
 			}
-			// Unordered list items
-			if lexed, nextFn := l.lexListItem(unorderedListItemStart, itemUnorderedListItemStart, itemListItemEnd, itemUnorderedDefinitionStart, orderedListItemStart, itemOrderedUnorderedStart); lexed {
-				return nextFn
-			}
-			// Ordered list items
-			if lexed, nextFn := l.lexListItem(orderedListItemStart, itemOrderedListItemStart, itemListItemEnd, itemOrderedDefinitionStart, unorderedListItemStart, itemUnorderedOrderedStart); lexed {
-				return nextFn
-			}
+		}
 
-			// If newline without header or another list item, end existing list item
-			if l.inListItem {
-				l.inListItem = false
-				l.emit(itemListItemEnd)
-			}
+		// Lex templates
+		if strings.HasPrefix(remaining, leftTemplate) {
+			l.emitAnyText()
+			return lexLeftTemplate
+		}
+		if strings.HasPrefix(remaining, rightTemplate) && l.inTemplate() {
+			l.emitAnyText()
+			return lexRightTemplate
 		}
 
-		for i := headers; i > 0; i-- {
-			delim := strings.Repeat(headerDelim, i)
-			if strings.HasPrefix(l.input[l.pos:], delim+"\n") || l.input[l.pos:] == delim {
-				if l.pos > l.start {
-					l.emit(itemText)
-				}
-				l.ignore()
-				return lexDelim(itemHeaderEnd, delim, i)
-			}
+		// Lex links
+		if strings.HasPrefix(remaining, leftLink) {
+			l.emitAnyText()
+			return lexLeftLink
 		}
-		/*
-			if strings.HasPrefix(l.input[l.pos:], listItemEndDelim) {
-				if l.pos > l.start {
-					l.emit(itemListItemPrefix)
-				}
-				l.pos += Pos(len(listItemEndDelim))
-				l.emit(itemListItemEnd)
-			}
-		*/
-		if strings.HasPrefix(l.input[l.pos:], leftTemplate) {
-			if l.pos > l.start {
-				l.emit(itemText)
+		if strings.HasPrefix(remaining, rightLink) && l.inScope(linkScope) {
+			l.emitAnyText()
+			return lexRightLink
+		}
+
+		// Lex markup
+		if strings.HasPrefix(remaining, strongEmphasized) {
+			l.emitAnyText()
+			return lexMarkup(itemStrongEmphasized, strongEmphasized)
+		}
+		if strings.HasPrefix(remaining, strong) {
+			l.emitAnyText()
+			return lexMarkup(itemStrong, strong)
+		}
+		if strings.HasPrefix(remaining, emphasized) {
+			l.emitAnyText()
+			return lexMarkup(itemEmphasized, emphasized)
+		}
+
+		// Lex HTML
+		if strings.HasPrefix(remaining, tagCommentLeft) {
+			l.emitAnyText()
+			return lexTagCommentLeft
+		}
+		if strings.HasPrefix(remaining, openTagLeft) && isTag(remaining) {
+			l.emitAnyText()
+			if strings.HasPrefix(remaining, closeTagLeft) {
+				return lexCloseTagLeft
 			}
-			return lexLeftTemplate
-		} else if strings.HasPrefix(l.input[l.pos:], rightTemplate) {
-			// TODO: Can this condition be removed?
-			// Need to properly support unclosed "}}".
-			if l.pos > l.start {
-				l.emit(itemText)
+			return lexOpenTagLeft
+		}
+
+		// Lex inline table cells
+		if l.tableDepth > 0 && len(l.scopes) == 0 {
+			if strings.HasPrefix(remaining, tableInlineCell) {
+				l.emitAnyText()
+				return lexDelim(itemTableCell, tableInlineCell, 0)
 			}
-			return lexRightTemplate
-		} else if strings.HasPrefix(l.input[l.pos:], leftLink) {
-			if l.pos > l.start {
-				l.emit(itemText)
+			if l.inHeaderCell && strings.HasPrefix(remaining, tableInlineHeaderCell) {
+				l.emitAnyText()
+				return lexDelim(itemTableHeaderCell, tableInlineHeaderCell, 0)
 			}
-			return lexLeftLink
 		}
 
+		// Lex remaining characters
 		switch r := l.next(); {
 		case r == eof:
-			l.drainTplBuffer()
-			if l.inListItem {
-				if l.pos > l.start {
-					l.emit(itemText)
-				}
-				l.emit(itemListItemEnd)
-				l.inListItem = false
+			if l.inTemplate() {
+				// Unclosed template -- eof.
+				l.emitAnyText()
+				l.drainTplBuffer()
 			}
+			l.endListItem()
 			break Loop
-		case isEndOfLine(r):
-			if l.pos > l.start {
-				l.emit(itemText)
+
+			// Template parameter delimiter
+		case r == '|':
+			if s := l.innermost(); s != nil && s.typ == templateScope {
+				l.backup()
+				l.emitAnyText()
+				l.advance()
+				l.emit(itemParamDelim)
+				s.named = false
+			}
+
+			// Template parameter name delimiter
+		case r == '=':
+			if s := l.innermost(); s != nil && s.typ == templateScope && !s.named {
+				l.backup()
+				l.emitTrim(itemParamName)
+				l.advance()
+				l.ignore()
+				s.named = true
 			}
-			continue Loop
-		default:
-			// absorb
 		}
 	}
-	// Correctly reached EOF.
-	if l.pos > l.start {
-		l.emit(itemText)
-	}
+
+	// Correctly reached EOF
+	l.emitAnyText()
 	l.emit(itemEOF)
+
+	return nil
+}
+
+// lexLineStart scans constructs that are only valid at the start of a line.
+// Returns nil if there are none.
+func lexLineStart(l *lexer) stateFn {
+	remaining := l.remaining()
+
+	l.inHeader = false
+	l.inHeaderCell = false
+
+	// Unclosed link -- end of line.
+	for l.inScope(linkScope) {
+		l.popScope(linkScope)
+	}
+
+	if l.inTemplate() {
+		if !strings.HasPrefix(remaining, headerDelim) {
+			return nil
+		}
+		// Unclosed template -- header start.
+		l.emitAnyText()
+		l.drainTplBuffer()
+	}
+
+	// Headers
+	for i := headers; i > 0; i-- {
+		delim := strings.Repeat(headerDelim, i)
+		if strings.HasPrefix(remaining, delim) {
+			l.endListItem()
+			l.emitAnyText()
+			l.inHeader = true
+			return lexDelim(itemHeaderStart, delim, i)
+		}
+	}
+
+	// Tables
+	if strings.HasPrefix(remaining, tableStart) {
+		l.endListItem()
+		l.emitAnyText()
+		l.tableDepth++
+		return lexTableLine(itemTableStart, tableStart)
+	}
+	if l.tableDepth > 0 {
+		switch {
+		case strings.HasPrefix(remaining, tableEnd):
+			l.emitAnyText()
+			l.tableDepth--
+			return lexDelim(itemTableEnd, tableEnd, 0)
+		case strings.HasPrefix(remaining, tableRow):
+			l.emitAnyText()
+			return lexTableLine(itemTableRow, tableRow)
+		case strings.HasPrefix(remaining, tableCaption):
+			l.emitAnyText()
+			return lexDelim(itemTableCaption, tableCaption, 0)
+		case strings.HasPrefix(remaining, tableCell):
+			l.emitAnyText()
+			return lexDelim(itemTableCell, tableCell, 0)
+		case strings.HasPrefix(remaining, tableHeaderCell):
+			l.emitAnyText()
+			l.inHeaderCell = true
+			return lexDelim(itemTableHeaderCell, tableHeaderCell, 0)
+		}
+	}
+
+	// Unordered list items
+	if next := l.lexListItem(unorderedListItemStart, itemUnorderedListItemStart, itemUnorderedDefinitionStart, orderedListItemStart, itemOrderedUnorderedStart); next != nil {
+		return next
+	}
+	// Ordered list items
+	if next := l.lexListItem(orderedListItemStart, itemOrderedListItemStart, itemOrderedDefinitionStart, unorderedListItemStart, itemUnorderedOrderedStart); next != nil {
+		return next
+	}
+
+	// If newline without another list item, end existing list item
+	l.endListItem()
+
 	return nil
 }
 
+// lexDelim scans a delimiter.
 func lexDelim(it itemType, delim string, depth int) stateFn {
 	return func(l *lexer) stateFn {
 		l.pos += Pos(len(delim))
@@ -635,10 +856,24 @@ func lexDelim(it itemType, delim string, depth int) stateFn {
 	}
 }
 
+// lexTableLine scans a table delimiter along with the attributes following it.
+func lexTableLine(it itemType, delim string) stateFn {
+	return func(l *lexer) stateFn {
+		l.pos += Pos(len(delim))
+		if i := strings.IndexByte(l.remaining(), '\n'); i != -1 {
+			l.pos += Pos(i)
+		} else {
+			l.pos = Pos(len(l.input))
+		}
+		l.emit(it)
+		return lexText
+	}
+}
+
 // lexLeftTemplate scans the left template delimiter.
 func lexLeftTemplate(l *lexer) stateFn {
-	l.buffered.buffering = true
 	l.pos += Pos(len(leftTemplate))
+	l.pushScope(templateScope)
 	l.emit(itemLeftTemplate)
 	return lexAction
 }
@@ -646,10 +881,8 @@ func lexLeftTemplate(l *lexer) stateFn {
 // lexRightTemplate scans the right template delimiter.
 func lexRightTemplate(l *lexer) stateFn {
 	l.pos += Pos(len(rightTemplate))
+	l.popScope(templateScope)
 	l.emit(itemRightTemplate)
-	if len(l.buffered.openTpls) == 0 {
-		l.drainTplBuffer()
-	}
 	return lexText
 }
 
@@ -659,362 +892,354 @@ func lexAction(l *lexer) stateFn {
 		switch r := l.next(); {
 		case r == eof:
 			// Unclosed template -- eof.
-			l.drainTplBuffer()
 			return lexText
 		case isEndOfLine(r):
-			if strings.HasPrefix(l.input[l.pos:], headerDelim) {
-				// Unclosed template -- header start.
+			// Actions may be followed by parameters on the next line, otherwise
+			// the template is unclosed.
+			next := strings.TrimLeft(l.remaining(), spaceChars)
+			if !strings.HasPrefix(next, paramDelim) && !strings.HasPrefix(next, rightTemplate) {
+				// Unclosed template -- invalid action.
 				l.backup()
-				if l.pos > l.start {
-					l.emit(itemAction)
-				}
+				l.emitAnyText()
 				l.drainTplBuffer()
 				return lexText
-			} else {
-				l.backup()
-				if l.pos > l.start {
-					l.emit(itemAction)
-				}
-				l.ignore()
-				return lexParam
-			}
-		case r == '}':
-			if strings.HasPrefix(l.input[l.pos:], "}") {
-				l.backup()
-				if l.pos > l.start {
-					l.emit(itemAction)
-				}
-				return lexRightTemplate
 			}
 		case r == '|':
 			l.backup()
 			if l.pos > l.start {
-				l.emit(itemAction)
-			}
-			return lexParam
-		default:
-			// absorb.
-		}
-	}
-	return lexText
-}
-
-// lexParam scans a template parameter.
-func lexParam(l *lexer) stateFn {
-	var inParam bool
-	var inNamedParam bool
-	var emittedEndOfLineParam bool
-
-	var openStartTag bool
-	var inTag bool
-	var openCloseTag bool
-	var openStartTagPos Pos
-
-	var inLink bool
-
-	for {
-		switch r := l.next(); {
-		case r == eof:
-			// Unclosed template -- eof.
-			l.backup()
-			if l.pos > l.start {
-				l.emit(itemParamText)
+				l.emitTrim(itemAction)
 			}
-			l.drainTplBuffer()
+			l.advance()
+			l.emit(itemParamDelim)
 			return lexText
-		case isEndOfLine(r):
-			if emittedEndOfLineParam {
-				emittedEndOfLineParam = false
-			} else {
-				if strings.HasPrefix(l.input[l.pos:], "|") || strings.HasPrefix(l.input[l.pos:], rightTemplate) {
-					l.backup()
-					if l.pos > l.start {
-						l.emit(itemParamText)
-						emittedEndOfLineParam = true
-					}
-					l.pos += Pos(1)
-					l.ignore()
-					inNamedParam = false
-				} else if strings.HasPrefix(l.input[l.pos:], "=") {
-					// Unclosed template -- header start.
-					l.backup()
-					if l.pos > l.start {
-						l.emit(itemParamText)
-					}
-					l.drainTplBuffer()
-					return lexText
-				}
-			}
-		case r == '<':
-			if openStartTag {
-				openStartTag = false
-			} else if inTag {
-				inTag = false
-				openCloseTag = true
-			} else {
-				openStartTag = true
-				openStartTagPos = l.pos
-			}
-		case r == '>':
-			if openStartTag {
-				tag := l.input[openStartTagPos : l.pos-1]
-				if _, ok := voidTagMap[tag]; !ok {
-					inTag = true
-				}
-				openStartTag = false
-			} else if inTag {
-				inTag = false
-			} else if openCloseTag {
-				openCloseTag = false
-			}
-		case r == '[':
-			if !inTag {
-				if n := l.peek(); n == '[' {
-					inLink = true
-				}
-			}
-			if openStartTag {
-				openStartTag = false
-			}
-			if openCloseTag {
-				openCloseTag = false
-			}
-		case r == ']':
-			if !inTag {
-				if n := l.peek(); n == ']' {
-					inLink = false
-				}
-			}
-			if openStartTag {
-				openStartTag = false
-			}
-			if openCloseTag {
-				openCloseTag = false
-			}
-		case r == '=':
-			if !inTag && !inNamedParam {
-				l.backup()
-				l.emit(itemParamName)
-				l.pos += Pos(len(paramEqual))
-				l.ignore()
-				inNamedParam = true
-			}
-			if openStartTag {
-				openStartTag = false
-			}
-			if openCloseTag {
-				openCloseTag = false
-			}
-		case r == '|':
-			if !inTag && !inLink {
-				if inParam {
-					l.backup()
-					if emittedEndOfLineParam {
-						emittedEndOfLineParam = false
-					} else {
-						l.emit(itemParamText)
-					}
-					l.pos += Pos(len(paramDelim))
-				}
-				l.emit(itemParamDelim)
-				inParam = true
-				inNamedParam = false
-			}
-			if openStartTag {
-				openStartTag = false
-			}
-			if openCloseTag {
-				openCloseTag = false
-			}
 		case r == '{':
-			if !inTag {
-				if strings.HasPrefix(l.input[l.pos:], "{") {
-					l.backup()
-					if l.pos > l.start {
-						l.emit(itemParamText)
-					}
-					return lexLeftTemplate
+			if strings.HasPrefix(l.remaining(), "{") {
+				l.backup()
+				if l.pos > l.start {
+					l.emitTrim(itemAction)
 				}
-			}
-			if openStartTag {
-				openStartTag = false
-			}
-			if openCloseTag {
-				openCloseTag = false
+				return lexLeftTemplate
 			}
 		case r == '}':
-			if strings.HasPrefix(l.input[l.pos:], "}") {
+			if strings.HasPrefix(l.remaining(), "}") {
 				l.backup()
-				if !emittedEndOfLineParam && l.pos > l.start {
-					l.emit(itemParamText)
+				if l.pos > l.start {
+					l.emitTrim(itemAction)
 				}
 				return lexRightTemplate
 			}
-			if openStartTag {
-				openStartTag = false
-			}
-			if openCloseTag {
-				openCloseTag = false
-			}
-		default:
-			if !isAlphaNumeric(r) {
-				if openStartTag {
-					openStartTag = false
-				}
-				if openCloseTag {
-					openCloseTag = false
-				}
-			}
-			if !inParam && !isWhitespace(r) {
-				// Unclosed template (invalid character)
-				l.backup()
-				l.ignore() // Ignore previous whitespace.
-				l.drainTplBuffer()
-				return lexText
-			}
-			// absorb.
 		}
 	}
-	return lexAction
 }
 
 // lexLeftLink scans the left link delimiter.
 func lexLeftLink(l *lexer) stateFn {
-	_ = "breakpoint"
-	l.buffered.buffering = true
 	l.pos += Pos(len(leftLink))
+	l.pushScope(linkScope)
 	l.emit(itemLeftLink)
 	return lexLink
 }
 
 // lexRightLink scans the right link delimiter.
 func lexRightLink(l *lexer) stateFn {
-	_ = "breakpoint"
 	l.pos += Pos(len(rightLink))
+	l.popScope(linkScope)
 	l.emit(itemRightLink)
-	l.drainLinkBuffer(false)
 	return lexText
 }
 
-// lexLink scans a link.
+// lexLink scans a link target.
 func lexLink(l *lexer) stateFn {
 	for {
 		switch r := l.next(); {
 		case r == eof:
-			_ = "breakpoint"
 			// Unclosed link -- eof.
-			if l.pos > l.start {
-				l.emit(itemLink)
-			}
-			l.drainLinkBuffer(true)
+			l.popScope(linkScope)
 			return lexText
-		case isEndOfLine(r):
-			_ = "breakpoint"
-			// Unclosed link -- end of line.
-			l.emit(itemLink)
-			l.drainLinkBuffer(true)
+		case isEndOfLine(r), r == '[', r == '{', r == '}':
+			// Invalid link -- end of line or template start/close.
+			l.backup()
+			l.popScope(linkScope)
 			return lexText
-		case r == '[', r == '{', r == '}':
-			_ = "breakpoint"
-			// Invalid link -- template start/close.
+		case r == '|':
 			l.backup()
 			if l.pos > l.start {
 				l.emit(itemLink)
 			}
-			l.drainLinkBuffer(true)
+			l.advance()
+			l.emit(itemLinkDelim)
 			return lexText
 		case r == ']':
-			_ = "breakpoint"
-			if strings.HasPrefix(l.input[l.pos:], "]") {
+			if strings.HasPrefix(l.remaining(), "]") {
 				l.backup()
 				if l.pos > l.start {
 					l.emit(itemLink)
 				}
 				return lexRightLink
 			}
-		case r == '|':
+		}
+	}
+}
+
+// lexMarkup scans bold and italic markup.
+func lexMarkup(it itemType, delim string) stateFn {
+	return func(l *lexer) stateFn {
+		l.pos += Pos(len(delim))
+		l.emit(it)
+		return lexText
+	}
+}
+
+// lexTagCommentLeft scans HTML comment left delimiters (<!--)
+func lexTagCommentLeft(l *lexer) stateFn {
+	l.pos += Pos(len(tagCommentLeft))
+	l.emit(itemTagCommentLeft)
+	return lexTagComment
+}
+
+// lexTagComment scans HTML comments
+func lexTagComment(l *lexer) stateFn {
+	i := strings.Index(l.remaining(), tagCommentRight)
+	if i == -1 {
+		// Unclosed comment -- eof.
+		l.pos = Pos(len(l.input))
+		if l.pos > l.start {
+			l.emit(itemTagComment)
+		}
+		return lexText
+	}
+	l.pos += Pos(i)
+	if l.pos > l.start {
+		l.emit(itemTagComment)
+	}
+	return lexTagCommentRight
+}
+
+// lexTagCommentRight scans HTML comment right delimiters (-->)
+func lexTagCommentRight(l *lexer) stateFn {
+	l.pos += Pos(len(tagCommentRight))
+	l.emit(itemTagCommentRight)
+	return lexText
+}
+
+// lexOpenTagLeft scans open HTML tag left delimiters (<)
+func lexOpenTagLeft(l *lexer) stateFn {
+	l.pos += Pos(len(openTagLeft))
+	l.closingTag = false
+	l.emit(itemOpenTagLeft)
+	return lexTagName
+}
+
+// lexCloseTagLeft scans close HTML tag left delimiters (</)
+func lexCloseTagLeft(l *lexer) stateFn {
+	l.pos += Pos(len(closeTagLeft))
+	l.closingTag = true
+	l.emit(itemCloseTagLeft)
+	return lexTagName
+}
+
+// lexCloseTagRight scans close HTML tag right delimiters (/>)
+func lexCloseTagRight(l *lexer) stateFn {
+	l.pos += Pos(len(closeTagRight))
+	l.emit(itemCloseTagRight)
+	return lexText
+}
+
+// lexTagRight scans HTML tag right delimiters (>)
+func lexTagRight(l *lexer) stateFn {
+	l.pos += Pos(len(tagRight))
+	l.emitTrim(itemTagRight)
+	if !l.closingTag && rawTags[l.tagName] {
+		return lexRawText
+	}
+	return lexText
+}
+
+// lexTagName scans HTML tag names (e.g. span)
+func lexTagName(l *lexer) stateFn {
+	for {
+		switch r := l.next(); {
+		case r == eof:
+			return lexText
+		case isWhitespace(r):
 			l.backup()
-			if l.pos > l.start {
-				l.emit(itemLink)
+			l.emitTagName()
+			return lexTagAttrName
+		case r == '/':
+			if strings.HasPrefix(l.remaining(), ">") {
+				l.backup()
+				l.emitTagName()
+				return lexCloseTagRight
 			}
-			l.pos += Pos(len(linkDelim))
-			l.emit(itemLinkDelim)
-			return lexLinkName
-		default:
-			_ = "breakpoint"
-			// absorb.
+		case r == '>':
+			l.backup()
+			l.emitTagName()
+			return lexTagRight
 		}
 	}
-	return lexText
 }
 
-// lexLinkName scans a link name.
-func lexLinkName(l *lexer) stateFn {
+// emitTagName emits an HTML tag name and remembers it.
+func (l *lexer) emitTagName() {
+	l.tagName = strings.ToLower(l.input[l.start:l.pos])
+	if l.pos > l.start {
+		l.emit(itemTagName)
+	}
+}
+
+// lexTagAttrName scans HTML tag attribute names (e.g. style)
+func lexTagAttrName(l *lexer) stateFn {
 	for {
 		switch r := l.next(); {
 		case r == eof:
-			_ = "breakpoint"
-			// Unclosed link name -- eof.
+			return lexText
+		case r == '=':
+			l.backup()
 			if l.pos > l.start {
-				l.emit(itemLinkName)
+				l.emitTrim(itemTagAttrName)
 			}
-			l.drainLinkBuffer(true)
+			l.advance()
+			l.ignore()
+			return lexTagAttrValueLeft
+		case r == '/':
+			if strings.HasPrefix(l.remaining(), ">") {
+				l.backup()
+				if strings.TrimSpace(l.input[l.start:l.pos]) != "" {
+					l.emitTrim(itemTagAttrName)
+				}
+				l.ignore()
+				return lexCloseTagRight
+			}
+		case r == '>':
+			l.backup()
+			if strings.TrimSpace(l.input[l.start:l.pos]) != "" {
+				l.emitTrim(itemTagAttrName)
+			}
+			l.ignore()
+			return lexTagRight
+		}
+	}
+}
+
+// lexTagAttrValueLeft scans HTML tag attribute left delimiter (")
+func lexTagAttrValueLeft(l *lexer) stateFn {
+	for {
+		switch r := l.next(); {
+		case r == eof:
 			return lexText
-		case isEndOfLine(r):
-			_ = "breakpoint"
-			// Unclosed link name -- end of line.
-			l.emit(itemLinkName)
-			l.drainLinkBuffer(true)
+		case r == '\'', r == '"':
+			l.quote = r
+			l.ignore()
+			return lexTagAttrValue
+		case isWhitespace(r):
+			l.ignore()
+		default:
+			l.backup()
+			l.quote = 0
+			return lexTagAttrValue
+		}
+	}
+}
+
+// lexTagAttrValue scans HTML tag attribute value (e.g. "color: red")
+func lexTagAttrValue(l *lexer) stateFn {
+	for {
+		switch r := l.next(); {
+		case r == eof:
 			return lexText
-		case r == '[', r == '{', r == '}':
-			_ = "breakpoint"
-			// Invalid link name -- template start/close.
+		case l.quote != 0 && r == l.quote:
 			l.backup()
 			if l.pos > l.start {
-				l.emit(itemLinkName)
+				l.emit(itemTagAttrValue)
 			}
-			l.drainLinkBuffer(true)
-			return lexText
-		case r == ']':
-			_ = "breakpoint"
-			if strings.HasPrefix(l.input[l.pos:], "]") {
+			l.advance()
+			l.ignore()
+			return lexTagAttrName
+		case l.quote == 0 && isWhitespace(r):
+			l.backup()
+			if l.pos > l.start {
+				l.emit(itemTagAttrValue)
+			}
+			return lexTagAttrName
+		case r == '/':
+			if strings.HasPrefix(l.remaining(), ">") {
 				l.backup()
 				if l.pos > l.start {
-					l.emit(itemLinkName)
+					l.emit(itemTagAttrValue)
 				}
-				return lexRightLink
+				return lexCloseTagRight
 			}
-		default:
-			_ = "breakpoint"
-			// absorb.
+		case r == '>':
+			l.backup()
+			if l.pos > l.start {
+				l.emit(itemTagAttrValue)
+			}
+			return lexTagRight
 		}
 	}
+}
+
+// lexRawText scans the contents of tags such as <nowiki> as text, up to the
+// matching closing tag.
+func lexRawText(l *lexer) stateFn {
+	i := indexCloseTag(l.remaining(), l.tagName)
+	if i == -1 {
+		// Unclosed tag -- eof.
+		l.pos = Pos(len(l.input))
+	} else {
+		l.pos += Pos(i)
+	}
+	l.emitAnyText()
 	return lexText
 }
 
+// indexCloseTag returns the index of the closing tag with the given name in
+// s, or -1 if it isn't present. Tag names are matched case-insensitively.
+func indexCloseTag(s, name string) int {
+	for i := 0; ; {
+		j := strings.Index(s[i:], closeTagLeft)
+		if j == -1 {
+			return -1
+		}
+		i += j
+		rest := s[i+len(closeTagLeft):]
+		if len(rest) >= len(name) && strings.EqualFold(rest[:len(name)], name) {
+			return i
+		}
+		i += len(closeTagLeft)
+	}
+}
+
+// isTag reports whether s starts with an HTML tag (e.g. <span> or </span>)
+// rather than a stray "<". Tags must be closed on the same line.
+func isTag(s string) bool {
+	name := strings.TrimPrefix(strings.TrimPrefix(s, openTagLeft), "/")
+	r, _ := utf8.DecodeRuneInString(name)
+	if !unicode.IsLetter(r) {
+		return false
+	}
+	end := strings.IndexByte(s, '>')
+	if end == -1 {
+		return false
+	}
+	if nl := strings.IndexByte(s, '\n'); nl != -1 && nl < end {
+		return false
+	}
+	return true
+}
+
 // isEndOfLine reports whether r is an end-of-line character.
 func isEndOfLine(r rune) bool {
 	return r == '\r' || r == '\n'
 }
 
-// isAlphaNumeric reports whether r is an ASCII letter or digit.
-func isAlphaNumeric(r rune) bool {
-	return (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
-}
-
 // isWhitespace reports whether r is a whitespace character.
 func isWhitespace(r rune) bool {
 	return (r == ' ' || r == '\t' || r == '\r' || r == '\n')
 }
 
-func init() {
-	for _, t := range voidTags {
-		voidTagMap[t] = true
-	}
-}
-
-// Simple stack implementation. Doesn't handle popping/peeking empty stacks
-// since it should only be called when the buffer can be balanced.
+// Simple stack implementation. Popping an empty stack isn't handled since it
+// should only be called when the buffer can be balanced.
 
 type stack []int
 
@@ -1028,6 +1253,10 @@ func (s *stack) pop() int {
 	return res
 }
 
+// peek returns the top of the stack, or -1 if the stack is empty.
 func (s *stack) peek() int {
+	if len(*s) == 0 {
+		return -1
+	}
 	return (*s)[len(*s)-1]
 }
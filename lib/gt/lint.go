@@ -0,0 +1,292 @@
+package gt
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/vthommeret/glossterm/lib/lang"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+// StrongPredicates are the quads from a word to the word it directly comes
+// from, as opposed to words it only mentions.
+var StrongPredicates = []string{
+	"inherited-from",
+	"borrowing-from",
+	"derived-from",
+	LoanPredicate(tpl.LearnedBorrowing),
+	LoanPredicate(tpl.OrthographicBorrowing),
+	LoanPredicate(tpl.SemanticLoan),
+	LoanPredicate(tpl.Calque),
+	LoanPredicate(tpl.PhonoSemanticMatching),
+}
+
+// BuildEtymologyGraph returns the graph of every word's etymology and
+// descendants in every language, with the same quads as the Latin-only graph
+// of BuildGraph.
+func BuildEtymologyGraph(words map[string]*Word) *Graph {
+	g, _ := etymologyGraph(func(fn func(*Word) error) error {
+		for _, w := range words {
+			if err := fn(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return g
+}
+
+// ReadEtymologyGraph returns the etymology graph of the words (gob or word
+// store) at path, see BuildEtymologyGraph.
+func ReadEtymologyGraph(path string) (*Graph, error) {
+	return etymologyGraph(func(fn func(*Word) error) error {
+		return ForEachWord(path, fn)
+	})
+}
+
+func etymologyGraph(forEach func(fn func(*Word) error) error) (*Graph, error) {
+	g := NewGraph()
+	err := forEach(func(w *Word) error {
+		for _, l := range w.Languages {
+			n := l.Code + "/" + w.Name
+			add := func(predicate, toLang, toWord string) {
+				if toLang != "" && toWord != "" {
+					g.AddEdge(n, predicate, toLang+"/"+toWord)
+				}
+			}
+			etymologyEdges(l.Etymology, add)
+			descendantEdges(l, add)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// GraphReport is the result of LintGraph, written as JSON so the entries can
+// be fixed upstream.
+type GraphReport struct {
+	Words int `json:"words"`
+	Edges int `json:"edges"`
+
+	// Words that come from each other through inherited, borrowed or
+	// derived quads.
+	Cycles []GraphCycle `json:"cycles"`
+
+	// Quads from a word to itself.
+	SelfLoops []CognateEdge `json:"selfLoops"`
+
+	// Descendants with an etymology that doesn't lead back to the word
+	// listing them.
+	UnreciprocatedDescendants []CognateEdge `json:"unreciprocatedDescendants"`
+
+	// Words that come from a word in a later language, e.g. Latin from
+	// Spanish.
+	LanguageOrder []LanguageOrderError `json:"languageOrder"`
+}
+
+// GraphCycle is a set of words that come from each other.
+type GraphCycle struct {
+	Words []string      `json:"words"`
+	Edges []CognateEdge `json:"edges"`
+}
+
+// LanguageOrderError is a quad from a word to an ancestor in a later period,
+// or to a descendant in an earlier one.
+type LanguageOrderError struct {
+	Edge       CognateEdge `json:"edge"`
+	FromPeriod string      `json:"fromPeriod"`
+	ToPeriod   string      `json:"toPeriod"`
+}
+
+// LintGraph checks an etymology graph (see BuildEtymologyGraph) for cycles,
+// self-loops, descendants that don't point back and words coming from
+// later languages.
+func LintGraph(g *Graph) *GraphReport {
+	r := &GraphReport{
+		Words:                     g.NodeCount(),
+		Edges:                     g.EdgeCount(),
+		Cycles:                    []GraphCycle{},
+		SelfLoops:                 []CognateEdge{},
+		UnreciprocatedDescendants: []CognateEdge{},
+		LanguageOrder:             []LanguageOrderError{},
+	}
+	strong := g.predicateSet(StrongPredicates)
+	children := g.predicateSet(ChildPredicates)
+
+	for _, e := range g.Quads() {
+		p := g.predicateIDs[e.Predicate]
+		if !strong[p] && !children[p] {
+			continue
+		}
+		edge := CognateEdge{From: e.Source, Predicate: e.Predicate, To: e.Target}
+		if e.Source == e.Target {
+			r.SelfLoops = append(r.SelfLoops, edge)
+			continue
+		}
+
+		// Ancestors should be from the same or an earlier period, and
+		// descendants from the same or a later one.
+		from, to := lang.PeriodOf(NewQuadNode(e.Source).Lang), lang.PeriodOf(NewQuadNode(e.Target).Lang)
+		if from != lang.UnknownPeriod && to != lang.UnknownPeriod {
+			if (strong[p] && to > from) || (!strong[p] && to < from) {
+				r.LanguageOrder = append(r.LanguageOrder, LanguageOrderError{Edge: edge, FromPeriod: from.String(), ToPeriod: to.String()})
+			}
+		}
+
+		if children[p] && !g.pointsBack(e.Target, e.Source) {
+			r.UnreciprocatedDescendants = append(r.UnreciprocatedDescendants, edge)
+		}
+	}
+
+	for _, c := range g.components(strong) {
+		if len(c) < 2 {
+			continue
+		}
+		r.Cycles = append(r.Cycles, g.cycle(c, strong))
+	}
+	sort.Slice(r.Cycles, func(i, j int) bool {
+		return r.Cycles[i].Words[0] < r.Cycles[j].Words[0]
+	})
+	return r
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *GraphReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (g *Graph) predicateSet(predicates []string) map[PredicateID]bool {
+	set := map[PredicateID]bool{}
+	for _, p := range predicates {
+		if id, ok := g.predicateIDs[p]; ok {
+			set[id] = true
+		}
+	}
+	return set
+}
+
+// pointsBack returns whether a descendant has no etymology, or one that
+// leads back to ancestor. Descendant and noncognate quads aren't followed.
+// Languages are compared by their parent, so e.g. Late Latin words point
+// back to Latin ones.
+func (g *Graph) pointsBack(descendant, ancestor string) bool {
+	id, ok := g.nodeIDs[descendant]
+	if !ok {
+		return true
+	}
+	skip := g.predicateSet(append([]string{NonCognatePredicate}, ChildPredicates...))
+	want := normalizeNode(ancestor)
+
+	hasEtymology := false
+	seen := map[NodeID]bool{id: true}
+	queue := []NodeID{id}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, e := range g.out[n] {
+			if skip[e.Predicate] || seen[e.Node] {
+				continue
+			}
+			hasEtymology = true
+			if normalizeNode(g.nodes[e.Node]) == want {
+				return true
+			}
+			seen[e.Node] = true
+			queue = append(queue, e.Node)
+		}
+	}
+	return !hasEtymology
+}
+
+// normalizeNode returns a lang/word with the language's parent, e.g.
+// "la/germanus" for "LL/germanus".
+func normalizeNode(id string) string {
+	n := NewQuadNode(id)
+	return lang.ToParent(n.Lang) + "/" + n.Word
+}
+
+// components returns the strongly connected components of the graph,
+// following only the predicates in set, using Tarjan's algorithm.
+func (g *Graph) components(set map[PredicateID]bool) [][]NodeID {
+	index := make([]int, len(g.nodes))
+	low := make([]int, len(g.nodes))
+	onStack := make([]bool, len(g.nodes))
+	var stack []NodeID
+	var components [][]NodeID
+	next := 1
+
+	var visit func(v NodeID)
+	visit = func(v NodeID) {
+		index[v], low[v] = next, next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, e := range g.out[v] {
+			if !set[e.Predicate] {
+				continue
+			}
+			if index[e.Node] == 0 {
+				visit(e.Node)
+				if low[e.Node] < low[v] {
+					low[v] = low[e.Node]
+				}
+			} else if onStack[e.Node] && index[e.Node] < low[v] {
+				low[v] = index[e.Node]
+			}
+		}
+		if low[v] == index[v] {
+			var c []NodeID
+			for {
+				n := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[n] = false
+				c = append(c, n)
+				if n == v {
+					break
+				}
+			}
+			components = append(components, c)
+		}
+	}
+	for v := range g.nodes {
+		if index[v] == 0 {
+			visit(NodeID(v))
+		}
+	}
+	return components
+}
+
+// cycle returns the sorted words of a component and the quads between them.
+func (g *Graph) cycle(c []NodeID, set map[PredicateID]bool) GraphCycle {
+	in := map[NodeID]bool{}
+	for _, n := range c {
+		in[n] = true
+	}
+	var cycle GraphCycle
+	for _, n := range c {
+		cycle.Words = append(cycle.Words, g.nodes[n])
+		for _, e := range g.out[n] {
+			if set[e.Predicate] && in[e.Node] && e.Node != n {
+				cycle.Edges = append(cycle.Edges, CognateEdge{From: g.nodes[n], Predicate: g.predicates[e.Predicate], To: g.nodes[e.Node]})
+			}
+		}
+	}
+	sort.Strings(cycle.Words)
+	sort.Slice(cycle.Edges, func(i, j int) bool {
+		a, b := cycle.Edges[i], cycle.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Predicate < b.Predicate
+	})
+	return cycle
+}
@@ -0,0 +1,105 @@
+package gt
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+func lintTestWords() map[string]*Word {
+	return map[string]*Word{
+		// Inherited from Latin and listed as its descendant.
+		"hermano": {Name: "hermano", Languages: map[string]*Language{"es": {
+			Code:      "es",
+			Etymology: &Etymology{Inherited: []tpl.Inherited{{Lang: "es", FromLang: "la", FromWord: "germanus"}}},
+		}}},
+		// Latin from Spanish, and listing a Spanish descendant whose
+		// etymology doesn't lead back.
+		"germanus": {Name: "germanus", Languages: map[string]*Language{"la": {
+			Code: "la",
+			Etymology: &Etymology{
+				Derived:  []tpl.Derived{{Lang: "la", FromLang: "la", FromWord: "germen"}},
+				Borrows:  []tpl.Borrow{{Lang: "la", FromLang: "es", FromWord: "hermano"}},
+				Mentions: []tpl.Mention{{Lang: "la", Word: "germanus"}},
+			},
+			Descendants: []tpl.Descendant{
+				{Lang: "es", Word: "hermano"},
+				{Lang: "es", Word: "germano"},
+				{Lang: "pt", Word: "irmão"},
+			},
+		}}},
+		"germano": {Name: "germano", Languages: map[string]*Language{"es": {
+			Code:      "es",
+			Etymology: &Etymology{Borrows: []tpl.Borrow{{Lang: "es", FromLang: "it", FromWord: "germano"}}},
+		}}},
+		// Derived from itself, and a calque of a Spanish word.
+		"germen": {Name: "germen", Languages: map[string]*Language{"la": {
+			Code: "la",
+			Etymology: &Etymology{
+				Derived: []tpl.Derived{{Lang: "la", FromLang: "la", FromWord: "germen"}},
+				Loans:   []tpl.Loan{{Lang: "la", FromLang: "es", FromWord: "germen", Kind: tpl.Calque}},
+			},
+		}}},
+	}
+}
+
+func TestLintGraph(t *testing.T) {
+	got := LintGraph(BuildEtymologyGraph(lintTestWords()))
+
+	want := &GraphReport{
+		Words: 7,
+		Edges: 10,
+		Cycles: []GraphCycle{{
+			Words: []string{"es/hermano", "la/germanus"},
+			Edges: []CognateEdge{
+				{From: "es/hermano", Predicate: "inherited-from", To: "la/germanus"},
+				{From: "la/germanus", Predicate: "borrowing-from", To: "es/hermano"},
+			},
+		}},
+		// Mentions aren't checked.
+		SelfLoops: []CognateEdge{
+			{From: "la/germen", Predicate: "derived-from", To: "la/germen"},
+		},
+		// pt/irmão isn't in the words, so it isn't reported.
+		UnreciprocatedDescendants: []CognateEdge{
+			{From: "la/germanus", Predicate: "inherited-descendant", To: "es/germano"},
+		},
+		LanguageOrder: []LanguageOrderError{
+			{
+				Edge:       CognateEdge{From: "la/germanus", Predicate: "borrowing-from", To: "es/hermano"},
+				FromPeriod: "ancient",
+				ToPeriod:   "modern",
+			},
+			{
+				Edge:       CognateEdge{From: "la/germen", Predicate: "calque-from", To: "es/germen"},
+				FromPeriod: "ancient",
+				ToPeriod:   "modern",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("gt.LintGraph(...) diff: %s", diff)
+	}
+}
+
+func TestGraphReportJSON(t *testing.T) {
+	got := LintGraph(NewGraph())
+
+	var b bytes.Buffer
+	if err := got.WriteJSON(&b); err != nil {
+		t.Fatalf("GraphReport.WriteJSON() got error: %s.", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatalf("Unable to decode report: %s", err)
+	}
+	// Empty lists are written as [] rather than null.
+	for _, k := range []string{"cycles", "selfLoops", "unreciprocatedDescendants", "languageOrder"} {
+		if _, ok := m[k].([]interface{}); !ok {
+			t.Errorf("GraphReport.WriteJSON() got %s %v, want [].", k, m[k])
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package gt
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// Migration upgrades gobs to Version from the version before it.
+type Migration struct {
+	Version     int
+	Description string
+
+	// Migrate decodes a gob of kind written at the previous version and
+	// returns it as it's written at Version. Older versions need their own
+	// copies of any types that changed since.
+	Migrate func(kind GobKind, dec *gob.Decoder) (interface{}, error)
+}
+
+var migrations = map[int]Migration{}
+
+// RegisterMigration adds a migration. It panics if there's already a
+// migration to the same version.
+func RegisterMigration(m Migration) {
+	if _, ok := migrations[m.Version]; ok {
+		panic(fmt.Sprintf("gt: migration to version %d registered twice", m.Version))
+	}
+	migrations[m.Version] = m
+}
+
+// Migrations returns the registered migrations in version order.
+func Migrations() []Migration {
+	var ms []Migration
+	for v := 1; v <= GobVersion; v++ {
+		if m, ok := migrations[v]; ok {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+func init() {
+	RegisterMigration(Migration{
+		Version:     1,
+		Description: "Add a header with the version and kind of gob.",
+		Migrate:     decodeCurrent,
+	})
+}
+
+// decodeCurrent decodes a gob of kind with the current types, for migrations
+// where the types didn't change.
+func decodeCurrent(kind GobKind, dec *gob.Decoder) (interface{}, error) {
+	switch kind {
+	case WordsGob:
+		var words map[string]*Word
+		err := dec.Decode(&words)
+		return words, err
+	case DescendantsGob:
+		var descendants map[string]Descendants
+		err := dec.Decode(&descendants)
+		return descendants, err
+	}
+	return nil, fmt.Errorf("unknown kind of gob %q", kind)
+}
+
+// migrateGob applies each migration after version to the gob being decoded,
+// and returns a decoder for the gob at GobVersion.
+func migrateGob(dec *gob.Decoder, version int, kind GobKind) (*gob.Decoder, error) {
+	if version > GobVersion {
+		return nil, fmt.Errorf("version %d is newer than %d", version, GobVersion)
+	}
+	for v := version + 1; v <= GobVersion; v++ {
+		m, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration to version %d", v)
+		}
+		data, err := m.Migrate(kind, dec)
+		if err != nil {
+			return nil, fmt.Errorf("unable to migrate to version %d: %s", v, err)
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+			return nil, fmt.Errorf("unable to encode version %d: %s", v, err)
+		}
+		dec = gob.NewDecoder(&buf)
+	}
+	return dec, nil
+}
+
+// MigrateGob upgrades the gob at path to GobVersion in place, returning the
+// version it was at. Kind is only needed for version 0 gobs, which don't say
+// what they contain.
+func MigrateGob(path string, kind GobKind) (int, error) {
+	version, headerKind, err := GobVersionOf(path)
+	if err != nil {
+		return 0, err
+	}
+	if version == GobVersion {
+		return version, nil
+	}
+	if headerKind != "" {
+		kind = headerKind
+	}
+
+	var data interface{}
+	switch kind {
+	case WordsGob:
+		var words map[string]*Word
+		err = ReadGob(path, &words)
+		data = words
+	case DescendantsGob:
+		var descendants map[string]Descendants
+		err = ReadGob(path, &descendants)
+		data = descendants
+	default:
+		return version, fmt.Errorf("unknown kind of gob %q", kind)
+	}
+	if err != nil {
+		return version, err
+	}
+
+	return version, WriteGob(path, data, false, false)
+}
@@ -0,0 +1,86 @@
+package gt
+
+import (
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+// writeUnversionedGob writes data the way WriteGob did before versioning.
+func writeUnversionedGob(t *testing.T, path string, data interface{}) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Unable to create %s: %s", path, err)
+	}
+	defer f.Close()
+	if err := gob.NewEncoder(f).Encode(data); err != nil {
+		t.Fatalf("Unable to encode %s: %s", path, err)
+	}
+}
+
+func TestMigrateGob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ignoreUnexported := cmpopts.IgnoreUnexported(Language{})
+	words := map[string]*Word{"libros": &exportWord}
+	descendants := map[string]Descendants{
+		"Template:etymtree/la/liber": {Word: "liber", Links: []tpl.Link{{Lang: "es", Word: "libro"}}},
+	}
+
+	wordsPath := filepath.Join(dir, "words.gob")
+	descendantsPath := filepath.Join(dir, "descendants.gob")
+	writeUnversionedGob(t, wordsPath, words)
+	writeUnversionedGob(t, descendantsPath, descendants)
+
+	// Unversioned gobs are migrated when read.
+	got, err := GetWords(wordsPath)
+	if err != nil {
+		t.Fatalf("gt.GetWords(%q) got error: %s.", wordsPath, err)
+	}
+	if diff := cmp.Diff(words, got, ignoreUnexported); diff != "" {
+		t.Errorf("gt.GetWords(%q) diff: %s", wordsPath, diff)
+	}
+
+	for _, tt := range []struct {
+		path string
+		kind GobKind
+	}{
+		{wordsPath, WordsGob},
+		{descendantsPath, DescendantsGob},
+	} {
+		from, err := MigrateGob(tt.path, tt.kind)
+		if err != nil {
+			t.Fatalf("gt.MigrateGob(%q) got error: %s.", tt.path, err)
+		}
+		if from != 0 {
+			t.Errorf("gt.MigrateGob(%q) got version %d, want 0.", tt.path, from)
+		}
+		version, kind, err := GobVersionOf(tt.path)
+		if err != nil {
+			t.Fatalf("gt.GobVersionOf(%q) got error: %s.", tt.path, err)
+		}
+		if version != GobVersion || kind != tt.kind {
+			t.Errorf("gt.GobVersionOf(%q) got %d, %q, want %d, %q.", tt.path, version, kind, GobVersion, tt.kind)
+		}
+	}
+
+	// Migrated gobs read the same, including from the compressed gob.
+	os.Remove(descendantsPath)
+	var gotDescendants map[string]Descendants
+	if err := ReadGob(descendantsPath, &gotDescendants); err != nil {
+		t.Fatalf("gt.ReadGob(%q) got error: %s.", descendantsPath, err)
+	}
+	if diff := cmp.Diff(descendants, gotDescendants); diff != "" {
+		t.Errorf("gt.ReadGob(%q) diff: %s", descendantsPath, diff)
+	}
+}
@@ -0,0 +1,179 @@
+package gt
+
+import (
+	"strings"
+
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+// Types of morphemes.
+const (
+	PrefixMorpheme    = "prefix"
+	RootMorpheme      = "root"
+	SuffixMorpheme    = "suffix"
+	InterfixMorpheme  = "interfix"
+	CircumfixMorpheme = "circumfix"
+)
+
+// Morpheme is a part of a word, e.g. the suffix "-ción" of "nación".
+type Morpheme struct {
+	Form  string `json:"form" firestore:"form"`
+	Type  string `json:"type" firestore:"type"`
+	Lang  string `json:"lang,omitempty" firestore:"lang,omitempty"`
+	Gloss string `json:"gloss,omitempty" firestore:"gloss,omitempty"`
+}
+
+// Key returns the morpheme as lang/form, e.g. "es/-ción".
+func (m Morpheme) Key() string {
+	return m.Lang + "/" + m.Form
+}
+
+// addMorphemes adds morphemes to the etymology's morphology, skipping ones
+// already listed.
+func (e *Etymology) addMorphemes(morphemes ...Morpheme) {
+	for _, m := range morphemes {
+		listed := false
+		for _, o := range e.Morphology {
+			if o.Form == m.Form && o.Type == m.Type && o.Lang == m.Lang {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			e.Morphology = append(e.Morphology, m)
+		}
+	}
+}
+
+// prefixMorphemes returns the morphemes of {{prefix}}, whose prefix is given
+// without a hyphen.
+func prefixMorphemes(p tpl.Prefix) []Morpheme {
+	var ms []Morpheme
+	if prefix := strings.TrimSuffix(p.Prefix, "-"); prefix != "" {
+		ms = append(ms, Morpheme{Form: prefix + "-", Type: PrefixMorpheme, Lang: p.Lang, Gloss: p.PrefixGloss})
+	}
+	if p.Root != "" && p.Root != "-" {
+		ms = append(ms, Morpheme{Form: p.Root, Type: RootMorpheme, Lang: p.Lang, Gloss: p.RootGloss})
+	}
+	return ms
+}
+
+// suffixMorphemes returns the morphemes of {{suffix}}, whose suffix is given
+// without a hyphen.
+func suffixMorphemes(s tpl.Suffix) []Morpheme {
+	var ms []Morpheme
+	if s.Root != "" && s.Root != "-" {
+		ms = append(ms, Morpheme{Form: s.Root, Type: RootMorpheme, Lang: s.Lang, Gloss: s.RootGloss})
+	}
+	if suffix := strings.TrimPrefix(s.Suffix, "-"); suffix != "" {
+		ms = append(ms, Morpheme{Form: "-" + suffix, Type: SuffixMorpheme, Lang: s.Lang, Gloss: s.SuffixGloss})
+	}
+	return ms
+}
+
+// affixMorphemes returns the morphemes of {{af}}, {{com}} and {{confix}}.
+// Blends and univerbations aren't split into morphemes.
+func affixMorphemes(a tpl.Affix) []Morpheme {
+	var ms []Morpheme
+	for i, p := range a.Parts {
+		if morphemeType(p.Word) == "" {
+			continue
+		}
+		m := Morpheme{Form: p.Word, Type: morphemeType(p.Word), Lang: a.PartLang(p), Gloss: p.Gloss}
+		switch a.Kind {
+		case tpl.AffixKind, tpl.Compound:
+		case tpl.Confix:
+			// A prefix and suffix around an optional root, with or without
+			// hyphens.
+			switch i {
+			case 0:
+				m.Form = strings.TrimSuffix(m.Form, "-") + "-"
+				m.Type = PrefixMorpheme
+			case len(a.Parts) - 1:
+				m.Form = "-" + strings.TrimPrefix(m.Form, "-")
+				m.Type = SuffixMorpheme
+			default:
+				m.Type = RootMorpheme
+			}
+		default:
+			return nil
+		}
+		ms = append(ms, m)
+	}
+	return ms
+}
+
+// morphemeType returns the type of a morpheme from its hyphens, e.g. "des-"
+// is a prefix, "-o-" an interfix and "ge- -t" a circumfix. A bare hyphen
+// isn't a morpheme and has no type.
+func morphemeType(form string) string {
+	if form == "" || form == "-" {
+		return ""
+	}
+	if parts := strings.Fields(form); len(parts) == 2 &&
+		strings.HasSuffix(parts[0], "-") && strings.HasPrefix(parts[1], "-") {
+		return CircumfixMorpheme
+	}
+	prefix, suffix := strings.HasPrefix(form, "-"), strings.HasSuffix(form, "-")
+	switch {
+	case prefix && suffix:
+		return InterfixMorpheme
+	case suffix:
+		return PrefixMorpheme
+	case prefix:
+		return SuffixMorpheme
+	}
+	return RootMorpheme
+}
+
+// MorphemeIndex maps morphemes, as lang/form, to the words using them, as
+// lang/word, e.g. "es/-ción" to "es/nación".
+type MorphemeIndex map[string][]string
+
+// BuildMorphemeIndex indexes the morphology of words.
+func BuildMorphemeIndex(words map[string]*Word) MorphemeIndex {
+	idx, _ := morphemeIndex(func(fn func(*Word) error) error {
+		for _, w := range words {
+			if err := fn(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return idx
+}
+
+// ReadMorphemeIndex indexes the morphology of the words (gob or word store)
+// at path, see BuildMorphemeIndex.
+func ReadMorphemeIndex(path string) (MorphemeIndex, error) {
+	return morphemeIndex(func(fn func(*Word) error) error {
+		return ForEachWord(path, fn)
+	})
+}
+
+func morphemeIndex(forEach func(fn func(*Word) error) error) (MorphemeIndex, error) {
+	idx := MorphemeIndex{}
+	err := forEach(func(w *Word) error {
+		for _, l := range w.Languages {
+			if l.Etymology == nil {
+				continue
+			}
+			for _, m := range l.Etymology.Morphology {
+				idx[m.Key()] = append(idx[m.Key()], l.Code+"/"+w.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for k, ws := range idx {
+		idx[k] = uniqueStrings(ws)
+	}
+	return idx, nil
+}
+
+// Words returns the words using a morpheme, e.g. "es" and "-ción".
+func (idx MorphemeIndex) Words(lang, form string) []string {
+	return idx[lang+"/"+form]
+}
@@ -0,0 +1,84 @@
+package gt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+var morphemeTypeTests = []struct {
+	form string
+	want string
+}{
+	{"nación", RootMorpheme},
+	{"des-", PrefixMorpheme},
+	{"-ción", SuffixMorpheme},
+	{"-o-", InterfixMorpheme},
+	{"ge- -t", CircumfixMorpheme},
+	{"-", ""},
+}
+
+func TestMorphemeType(t *testing.T) {
+	for _, tt := range morphemeTypeTests {
+		if got := morphemeType(tt.form); got != tt.want {
+			t.Errorf("gt.morphemeType(%q) got %q, want %q.", tt.form, got, tt.want)
+		}
+	}
+}
+
+func TestAffixMorphemes(t *testing.T) {
+	got := affixMorphemes(tpl.Affix{
+		Lang: "en",
+		Parts: []tpl.AffixPart{
+			{Word: "speed"},
+			{Word: "-o-"},
+			{Word: "-"},
+			{Word: "metrum", Lang: "la", Gloss: "measure"},
+		},
+		Kind: tpl.Compound,
+	})
+	want := []Morpheme{
+		{Form: "speed", Type: RootMorpheme, Lang: "en"},
+		{Form: "-o-", Type: InterfixMorpheme, Lang: "en"},
+		{Form: "metrum", Type: RootMorpheme, Lang: "la", Gloss: "measure"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("gt.affixMorphemes(...) diff: %s", diff)
+	}
+
+	// Blends aren't split into morphemes.
+	if got := affixMorphemes(tpl.Affix{Lang: "en", Parts: []tpl.AffixPart{{Word: "smoke"}, {Word: "fog"}}, Kind: tpl.Blend}); got != nil {
+		t.Errorf("gt.affixMorphemes(...) got %+v for a blend, want none.", got)
+	}
+}
+
+func TestBuildMorphemeIndex(t *testing.T) {
+	suffix := Morpheme{Form: "-ción", Type: SuffixMorpheme, Lang: "es"}
+	words := map[string]*Word{
+		"nación": {Name: "nación", Languages: map[string]*Language{"es": {
+			Code: "es",
+			Etymology: &Etymology{Morphology: []Morpheme{
+				{Form: "nacer", Type: RootMorpheme, Lang: "es"},
+				suffix,
+			}},
+		}}},
+		"canción": {Name: "canción", Languages: map[string]*Language{"es": {
+			Code:      "es",
+			Etymology: &Etymology{Morphology: []Morpheme{suffix}},
+		}}},
+		"canto": {Name: "canto", Languages: map[string]*Language{"es": {Code: "es"}}},
+	}
+	idx := BuildMorphemeIndex(words)
+
+	want := MorphemeIndex{
+		"es/-ción": {"es/canción", "es/nación"},
+		"es/nacer": {"es/nación"},
+	}
+	if diff := cmp.Diff(want, idx); diff != "" {
+		t.Errorf("gt.BuildMorphemeIndex(...) diff: %s", diff)
+	}
+	if diff := cmp.Diff(want["es/-ción"], idx.Words("es", "-ción")); diff != "" {
+		t.Errorf("MorphemeIndex.Words(%q, %q) diff: %s", "es", "-ción", diff)
+	}
+}
@@ -14,6 +14,9 @@ type Word struct {
 	Name      string               `json:"name"`
 	Languages map[string]*Language `json:"languages"`
 	Indexed   *time.Time           `json:"indexed,omitempty"`
+	// Digest of the word as parsed, before desctrees, etymtrees and cognates
+	// are added, so Changes.Apply can tell whether the page changed.
+	Digest string `json:"-" firestore:"-"`
 }
 
 type Language struct {
@@ -27,7 +30,10 @@ type Language struct {
 	// TODO: Rename EtymTrees. May need to re-write DB.
 	DescendantTrees []tpl.EtymTree `json:"descendantTrees,omitempty" firestore:"descendantTrees,omitempty"`
 	DescTrees       []tpl.DescTree `json:"descTrees,omitempty" firestore:"descTrees,omitempty"`
-	Cognates        []*Cognate     `json:"cognates,omitempty" firestore:"cognates,omitempty"`
+	// Descendants as listed, with the descendants of DescTrees added by
+	// ResolveDescTrees.
+	DescendantTree []*DescendantNode `json:"descendantTree,omitempty" firestore:"descendantTree,omitempty"`
+	Cognates       []*Cognate        `json:"cognates,omitempty" firestore:"cognates,omitempty"`
 
 	section      sectionType
 	subSection   sectionType
@@ -43,8 +49,12 @@ type Language struct {
 
 	linkBuffer *LinkBuffer
 
-	definitionBuffer TextBuffer
-	definitionRoot   *RootWord
+	descendantTree descendantTreeBuilder
+
+	definitionBuffer     *textRenderer
+	definitionRoot       *RootWord
+	definitionReferences []string
+	referenceBuffer      *textRenderer
 }
 
 type Etymology struct {
@@ -56,6 +66,18 @@ type Etymology struct {
 	Prefixes  []tpl.Prefix    `json:"prefixes,omitempty" firestore:"prefixes,omitempty"`
 	Suffixes  []tpl.Suffix    `json:"suffixes,omitempty" firestore:"suffixes,omitempty"`
 	Links     []tpl.Link      `json:"links,omitempty" firestore:"links,omitempty"`
+
+	// Relations from newer templates, each with its kind, e.g. a calque
+	// or a compound.
+	Loans       []tpl.Loan       `json:"loans,omitempty" firestore:"loans,omitempty"`
+	Affixes     []tpl.Affix      `json:"affixes,omitempty" firestore:"affixes,omitempty"`
+	Related     []tpl.Related    `json:"related,omitempty" firestore:"related,omitempty"`
+	Roots       []tpl.Root       `json:"roots,omitempty" firestore:"roots,omitempty"`
+	NonCognates []tpl.NonCognate `json:"nonCognates,omitempty" firestore:"nonCognates,omitempty"`
+
+	// Every morpheme from {{prefix}}, {{suffix}}, {{af}}, {{com}} and
+	// {{confix}}.
+	Morphology []Morpheme `json:"morphology,omitempty" firestore:"morphology,omitempty"`
 }
 
 type LinkBuffer struct {
@@ -80,8 +102,10 @@ type Definitions struct {
 }
 
 type Definition struct {
-	Text string    `json:"text" firestore:"text"`
-	Root *RootWord `json:"root,omitempty" firestore:"root,omitempty"`
+	Text       string    `json:"text" firestore:"text"`
+	Spans      []Span    `json:"spans,omitempty" firestore:"spans,omitempty"`
+	Root       *RootWord `json:"root,omitempty" firestore:"root,omitempty"`
+	References []string  `json:"references,omitempty" firestore:"references,omitempty"`
 }
 
 type RootWord struct {
@@ -175,6 +199,21 @@ func (l *Language) IsEmpty() bool {
 		if l.Etymology.Suffixes != nil {
 			return false
 		}
+		if l.Etymology.Loans != nil {
+			return false
+		}
+		if l.Etymology.Affixes != nil {
+			return false
+		}
+		if l.Etymology.Related != nil {
+			return false
+		}
+		if l.Etymology.Roots != nil {
+			return false
+		}
+		if l.Etymology.NonCognates != nil {
+			return false
+		}
 	}
 	if l.Links != nil {
 		return false
@@ -190,89 +229,93 @@ func (l *Language) IsEmpty() bool {
 
 func (l *Language) flushDefinition() {
 	if l.definitionBuffer != nil {
-		definition := strings.TrimSpace(strings.Join(l.definitionBuffer, ""))
+		definition := Definition{
+			Text:       l.definitionBuffer.String(),
+			Spans:      l.definitionBuffer.Spans(),
+			Root:       l.definitionRoot,
+			References: l.definitionReferences,
+		}
 
-		root := l.definitionRoot
-		if definition != "" {
+		if definition.Text != "" {
 			switch l.section {
 			case nounSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Nouns =
-					append(l.Definitions.Nouns, Definition{Text: definition, Root: root})
+					append(l.Definitions.Nouns, definition)
 			case adjectiveSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Adjectives =
-					append(l.Definitions.Adjectives, Definition{Text: definition, Root: root})
+					append(l.Definitions.Adjectives, definition)
 			case verbSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Verbs =
-					append(l.Definitions.Verbs, Definition{Text: definition, Root: root})
+					append(l.Definitions.Verbs, definition)
 			case adverbSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Adverbs =
-					append(l.Definitions.Adverbs, Definition{Text: definition, Root: root})
+					append(l.Definitions.Adverbs, definition)
 			case articleSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Articles =
-					append(l.Definitions.Articles, Definition{Text: definition, Root: root})
+					append(l.Definitions.Articles, definition)
 			case prepositionSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Prepositions =
-					append(l.Definitions.Prepositions, Definition{Text: definition, Root: root})
+					append(l.Definitions.Prepositions, definition)
 			case pronounSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Pronouns =
-					append(l.Definitions.Pronouns, Definition{Text: definition, Root: root})
+					append(l.Definitions.Pronouns, definition)
 			case conjunctionSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Conjunctions =
-					append(l.Definitions.Conjunctions, Definition{Text: definition, Root: root})
+					append(l.Definitions.Conjunctions, definition)
 			case interjectionSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Interjections =
-					append(l.Definitions.Interjections, Definition{Text: definition, Root: root})
+					append(l.Definitions.Interjections, definition)
 			case numeralSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Numerals =
-					append(l.Definitions.Numerals, Definition{Text: definition, Root: root})
+					append(l.Definitions.Numerals, definition)
 			case numberSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Numbers =
-					append(l.Definitions.Numbers, Definition{Text: definition, Root: root})
+					append(l.Definitions.Numbers, definition)
 			case particleSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Particles =
-					append(l.Definitions.Particles, Definition{Text: definition, Root: root})
+					append(l.Definitions.Particles, definition)
 			case determinerSection:
 				if l.Definitions == nil {
 					l.Definitions = &Definitions{}
 				}
 				l.Definitions.Determiners =
-					append(l.Definitions.Determiners, Definition{Text: definition, Root: root})
+					append(l.Definitions.Determiners, definition)
 			}
 		}
 	}
@@ -283,12 +326,64 @@ func (l *Language) flushDefinition() {
 
 	l.definitionBuffer = nil
 	l.definitionRoot = nil
+	l.definitionReferences = nil
+	l.referenceBuffer = nil
+}
+
+// descendants returns the builder of the language's DescendantTree.
+func (l *Language) descendants() *descendantTreeBuilder {
+	if l.descendantTree.roots == nil {
+		l.descendantTree.roots = &l.DescendantTree
+	}
+	return &l.descendantTree
+}
+
+// addDescendant adds a descendant to the DescendantTree at the current list
+// depth.
+func (l *Language) addDescendant(n *DescendantNode) {
+	l.descendants().add(l.listItemDepth, n)
 }
 
 func (l *Language) shouldDefineLink() bool {
 	return l.definitionBuffer != nil && l.listItemDepth == 1 && !l.inListItemDefinition && !l.inListItemSublist
 }
 
+// defineLink adds the buffered link's name to the definition.
+func (l *Language) defineLink() {
+	if !l.shouldDefineLink() {
+		return
+	}
+	word, code := wikiLink(l.linkBuffer.Link)
+	if l.linkBuffer.Name != nil {
+		l.definitionBuffer.linkTo(*l.linkBuffer.Name, code, word)
+	} else {
+		l.definitionBuffer.linkTo(l.linkBuffer.Link, code, word)
+	}
+}
+
+// inDefinition reports whether items are part of a definition.
+func (l *Language) inDefinition() bool {
+	return definitionSection(l.section) && l.listItemDepth == 1 && !l.inListItemDefinition && !l.inListItemSublist
+}
+
+// definition returns the definition being rendered, starting one if needed.
+func (l *Language) definition() *textRenderer {
+	if l.definitionBuffer == nil {
+		l.definitionBuffer = &textRenderer{}
+	}
+	return l.definitionBuffer
+}
+
+// flushReference adds a rendered <ref> tag to the current definition.
+func (l *Language) flushReference() {
+	if l.referenceBuffer != nil {
+		if reference := l.referenceBuffer.String(); reference != "" && l.inDefinition() {
+			l.definitionReferences = append(l.definitionReferences, reference)
+		}
+	}
+	l.referenceBuffer = nil
+}
+
 type sectionType int
 
 const (
@@ -521,6 +616,100 @@ type ListItem struct {
 
 type TextBuffer []string
 
+// templateBuffer builds a template from the items within it. Nested templates
+// aren't supported for now, so a template containing another is discarded.
+type templateBuffer struct {
+	depth    int
+	template *tpl.Template
+	param    *tpl.Parameter
+	value    TextBuffer
+	inParam  bool
+}
+
+// open starts a template, or a template nested within the current one.
+func (b *templateBuffer) open() {
+	b.depth++
+	if b.depth == 1 {
+		*b = templateBuffer{depth: 1, template: &tpl.Template{}}
+	} else {
+		b.template = nil
+	}
+}
+
+// close ends a template, returning it if it was an outermost template without
+// nested templates.
+func (b *templateBuffer) close() *tpl.Template {
+	b.depth--
+	if b.depth > 0 || b.template == nil {
+		if b.depth < 0 {
+			b.depth = 0
+		}
+		return nil
+	}
+	b.flushParam()
+	t := b.template
+	b.template = nil
+	return t
+}
+
+// add adds an item within the template. Parameter values are kept as
+// wikitext, except for HTML tags and comments which are dropped.
+func (b *templateBuffer) add(i item) {
+	if b.template == nil {
+		return
+	}
+	switch i.typ {
+	case itemAction:
+		b.template.Action = i.val
+	case itemParamDelim:
+		b.flushParam()
+		b.inParam = true
+	case itemParamName:
+		b.param = &tpl.Parameter{Name: i.val}
+	case itemText, itemLeftLink, itemLink, itemLinkDelim, itemRightLink, itemStrongEmphasized, itemStrong, itemEmphasized:
+		b.value = append(b.value, i.val)
+	}
+}
+
+func (b *templateBuffer) flushParam() {
+	if !b.inParam {
+		return
+	}
+	value := strings.TrimSpace(strings.Join(b.value, ""))
+	if b.param != nil {
+		b.param.Value = value
+		b.template.NamedParameters = append(b.template.NamedParameters, *b.param)
+	} else {
+		b.template.Parameters = append(b.template.Parameters, value)
+	}
+	b.param = nil
+	b.value = nil
+	b.inParam = false
+}
+
+// htmlTag is the HTML tag whose items are currently being read.
+type htmlTag struct {
+	name    string
+	closing bool
+}
+
+// isTemplateItem reports whether t can occur within a template.
+func isTemplateItem(t itemType) bool {
+	return t != itemEOF && t != itemError && t != itemLeftTemplate && t != itemRightTemplate
+}
+
+// isRefItem reports whether t should be parsed within a <ref> tag. Other items
+// are only rendered as part of the reference.
+func isRefItem(t itemType) bool {
+	switch t {
+	case itemEOF, itemError, itemHeaderStart, itemHeaderEnd, itemLeftTemplate, itemRightTemplate,
+		itemOpenTagLeft, itemCloseTagLeft, itemTagName, itemTagRight, itemCloseTagRight,
+		itemUnorderedListItemStart, itemOrderedListItemStart, itemListItemEnd:
+		return true
+	}
+	return false
+}
+
 func init() {
 	wordTypeRegex = regexp.MustCompile("^([^0-9]+)(?: [0-9]+)?$")
 
@@ -540,6 +729,12 @@ func definitionSection(section sectionType) bool {
 
 // Parses a given word (e.g. https://en.wiktionary.org/wiki/hombre).
 func ParseWord(p Page, langMap map[string]bool) (Word, error) {
+	return ParseWordDiagnostics(p, langMap, nil)
+}
+
+// ParseWordDiagnostics parses a given word, adding templates, sections,
+// labels and form-of templates it doesn't handle to diagnostics.
+func ParseWordDiagnostics(p Page, langMap map[string]bool, diagnostics *Diagnostics) (Word, error) {
 	name := p.Title
 	text := p.Text
 
@@ -555,20 +750,34 @@ func ParseWord(p Page, langMap map[string]bool) (Word, error) {
 	var inSectionHeader bool
 
 	var language *Language
-	var template *tpl.Template
-	var namedParam *tpl.Parameter
+	var templates templateBuffer
 
-	var templateDepth int
+	var tag htmlTag
+	var refDepth int
 
 	l := NewLexer(text)
+	defer l.Stop()
 
 Parse:
 	for {
 		i := l.NextItem()
 
+		if templates.depth > 0 && isTemplateItem(i.typ) {
+			templates.add(i)
+			continue
+		}
+
+		// Citations aren't part of definitions or etymologies.
+		if refDepth > 0 && !isRefItem(i.typ) {
+			if language != nil && language.referenceBuffer != nil {
+				language.referenceBuffer.add(i)
+			}
+			continue
+		}
+
 		switch i.typ {
 		case itemError:
-			return Word{}, fmt.Errorf("unable to parse: %s", i.val)
+			return Word{}, l.errorAt(name, LexError, i, fmt.Sprintf("unable to lex: %s", i.val))
 		case itemEOF:
 			if language != nil {
 				if !language.IsEmpty() {
@@ -580,6 +789,10 @@ Parse:
 			}
 			break Parse
 		case itemHeaderStart:
+			refDepth = 0
+			if language != nil {
+				language.referenceBuffer = nil
+			}
 			if i.depth == 1 {
 				language = nil
 				inLanguageHeader = false
@@ -613,6 +826,9 @@ Parse:
 		case itemUnorderedListItemStart:
 			if language != nil {
 				language.listItemDepth = i.depth
+				if language.subSection == descendantsSection {
+					language.descendants().startItem(i.depth)
+				}
 			}
 		case itemOrderedListItemStart:
 			if language != nil {
@@ -636,9 +852,18 @@ Parse:
 			}
 		case itemListItemEnd:
 			if language != nil {
+				if language.linkBuffer != nil {
+					// Unclosed link
+					language.defineLink()
+					language.linkBuffer = nil
+				}
+				language.flushReference()
 				language.flushDefinition()
 				language.etylLang = nil
 				language.descendantLang = nil
+				if language.subSection == descendantsSection {
+					language.descendants().endItem()
+				}
 				if language.listItem != nil {
 					language.Links =
 						append(language.Links, language.listItem.TplLinks(langMap, w.Name)...)
@@ -652,24 +877,19 @@ Parse:
 			if language != nil {
 				if language.listItem != nil {
 					language.listItem.Links = append(language.listItem.Links, i.val)
-				} else {
+				} else if language.linkBuffer != nil {
 					language.linkBuffer.Link = i.val
 				}
 			}
-		case itemLinkName:
-			if language != nil {
-				language.linkBuffer.Name = &i.val
+		case itemLinkDelim:
+			if language != nil && language.linkBuffer != nil {
+				var name string
+				language.linkBuffer.Name = &name
 			}
 		case itemRightLink:
-			if language != nil {
+			if language != nil && language.linkBuffer != nil {
 				if language.shouldDefineLink() {
-					var link string
-					if language.linkBuffer.Name != nil {
-						link = *language.linkBuffer.Name
-					} else {
-						link = language.linkBuffer.Link
-					}
-					language.definitionBuffer = append(language.definitionBuffer, link)
+					language.defineLink()
 				} else if language.section == etymologySection {
 					if language.etylLang != nil {
 						tplLink := toTplLink(langMap, *language.etylLang, language.linkBuffer.Link, w.Name)
@@ -685,14 +905,55 @@ Parse:
 						tplLink := toTplLink(langMap, *language.descendantLang, language.linkBuffer.Link, w.Name)
 						if tplLink != nil {
 							language.Links = append(language.Links, *tplLink)
+							language.addDescendant(&DescendantNode{Lang: tplLink.Lang, Word: tplLink.Word})
 						}
 					}
 				}
 				language.linkBuffer = nil
 				language.etylLang = nil
 			}
+		case itemOpenTagLeft:
+			tag = htmlTag{}
+		case itemCloseTagLeft:
+			tag = htmlTag{closing: true}
+		case itemTagName:
+			tag.name = strings.ToLower(i.val)
+		case itemTagRight:
+			if tag.name == "ref" {
+				if !tag.closing {
+					refDepth++
+					if refDepth == 1 && language != nil {
+						language.referenceBuffer = &textRenderer{}
+					}
+				} else if refDepth > 0 {
+					refDepth--
+					if refDepth == 0 && language != nil {
+						language.flushReference()
+					}
+				}
+			} else if refDepth > 0 {
+				if language != nil && language.referenceBuffer != nil {
+					language.referenceBuffer.htmlTag(tag)
+				}
+			} else if language != nil && language.definitionBuffer != nil && language.shouldDefineLink() {
+				language.definitionBuffer.htmlTag(tag)
+			}
+		case itemCloseTagRight:
+			if refDepth > 0 {
+				if language != nil && language.referenceBuffer != nil {
+					language.referenceBuffer.htmlTag(tag)
+				}
+			} else if language != nil && language.definitionBuffer != nil && language.shouldDefineLink() {
+				language.definitionBuffer.htmlTag(tag)
+			}
+		case itemStrongEmphasized, itemStrong, itemEmphasized:
+			if language != nil && language.linkBuffer == nil && language.inDefinition() {
+				language.definition().markup(i.typ)
+			}
 		case itemText:
-			if inLanguageHeader {
+			if language != nil && language.linkBuffer != nil && language.linkBuffer.Name != nil {
+				*language.linkBuffer.Name += i.val
+			} else if inLanguageHeader && language != nil {
 				if l, ok := lang.CanonicalLangs[i.val]; ok {
 					if _, ok := langMap[l.Code]; ok {
 						language.Code = l.Code
@@ -724,29 +985,32 @@ Parse:
 
 						if language.sectionDepth >= 3 && i.val == "Descendants" {
 							language.subSection = descendantsSection
+							language.descendants().reset()
 						} else {
 							language.subSection = unknownSection
+							diagnostics.Add(UnhandledSection, language.Code, i.val, name)
 						}
 					}
 				}
-			} else if language != nil && definitionSection(language.section) && language.listItemDepth == 1 && !language.inListItemDefinition && !language.inListItemSublist {
-				language.definitionBuffer = append(language.definitionBuffer, i.val)
+			} else if language != nil && language.inDefinition() {
+				language.definition().text(i.val)
+			} else if language != nil && language.subSection == descendantsSection {
+				language.descendants().text(i.val, langMap)
 			}
 		case itemLeftTemplate:
-			templateDepth++
-			if templateDepth == 1 {
-				template = &tpl.Template{}
-			} else {
-				// Nested templates aren't supported for now.
-				template = nil
-			}
+			templates.open()
 		case itemRightTemplate:
-			templateDepth--
-
-			namedParam = nil
+			template := templates.close()
 
 			// Don't support nested templates for now
-			if language == nil || template == nil || templateDepth != 0 {
+			if language == nil || template == nil {
+				break
+			}
+
+			if refDepth > 0 {
+				if language.referenceBuffer != nil {
+					language.referenceBuffer.template(template)
+				}
 				break
 			}
 
@@ -813,6 +1077,7 @@ Parse:
 						}
 						language.Etymology.Prefixes =
 							append(language.Etymology.Prefixes, prefix)
+						language.Etymology.addMorphemes(prefixMorphemes(prefix)...)
 					}
 				case "suffix":
 					suffix := template.ToSuffix()
@@ -822,6 +1087,58 @@ Parse:
 						}
 						language.Etymology.Suffixes =
 							append(language.Etymology.Suffixes, suffix)
+						language.Etymology.addMorphemes(suffixMorphemes(suffix)...)
+					}
+				case "lbor", "learned borrowing", "obor", "orthographic borrowing",
+					"sl", "semantic loan", "cal", "calque", "clq", "psm", "phono-semantic matching":
+					loan := template.ToLoan()
+					if _, ok := langMap[loan.Lang]; ok && validWord(loan.FromWord) {
+						if _, ok := langMap[loan.FromLang]; ok {
+							if language.Etymology == nil {
+								language.Etymology = &Etymology{}
+							}
+							language.Etymology.Loans =
+								append(language.Etymology.Loans, loan)
+						}
+					}
+				case "af", "affix", "com", "compound", "confix", "blend", "univerbation":
+					affix := template.ToAffix()
+					if _, ok := langMap[affix.Lang]; ok && len(affix.Parts) > 0 {
+						if language.Etymology == nil {
+							language.Etymology = &Etymology{}
+						}
+						language.Etymology.Affixes =
+							append(language.Etymology.Affixes, affix)
+						language.Etymology.addMorphemes(affixMorphemes(affix)...)
+					}
+				case "doublet", "back-form", "back-formation", "bf", "clipping", "clip":
+					related := template.ToRelated()
+					if _, ok := langMap[related.Lang]; ok && len(related.Words) > 0 {
+						if language.Etymology == nil {
+							language.Etymology = &Etymology{}
+						}
+						language.Etymology.Related =
+							append(language.Etymology.Related, related)
+					}
+				case "root":
+					// Roots are usually in proto-languages, which aren't in
+					// langMap.
+					root := template.ToRoot()
+					if _, ok := langMap[root.Lang]; ok && len(root.Roots) > 0 {
+						if language.Etymology == nil {
+							language.Etymology = &Etymology{}
+						}
+						language.Etymology.Roots =
+							append(language.Etymology.Roots, root)
+					}
+				case "noncog", "noncognate", "nc":
+					nonCognate := template.ToNonCognate()
+					if _, ok := langMap[nonCognate.Lang]; ok && validWord(nonCognate.Word) {
+						if language.Etymology == nil {
+							language.Etymology = &Etymology{}
+						}
+						language.Etymology.NonCognates =
+							append(language.Etymology.NonCognates, nonCognate)
 					}
 				case "etyl":
 					etyl := template.ToEtyl()
@@ -829,6 +1146,8 @@ Parse:
 						language.etylLang = &etyl.Lang
 						setEtylLang = true
 					}
+				default:
+					diagnostics.Add(UnknownTemplate, language.Code, template.Action, name)
 				}
 				if !setEtylLang {
 					language.etylLang = nil
@@ -841,6 +1160,11 @@ Parse:
 						language.Descendants =
 							append(language.Descendants, desc)
 						language.descendantLang = &desc.Lang
+						language.addDescendant(&DescendantNode{
+							Lang:     desc.Lang,
+							Word:     desc.Word,
+							Relation: DescendantRelation(desc),
+						})
 					}
 				case "l", "link":
 					link := template.ToLink()
@@ -848,6 +1172,7 @@ Parse:
 						language.Links =
 							append(language.Links, link)
 						language.descendantLang = &link.Lang
+						language.addDescendant(&DescendantNode{Lang: link.Lang, Word: link.Word})
 					}
 				case "desctree", "descendants tree":
 					descTree := template.ToDescTree()
@@ -856,8 +1181,14 @@ Parse:
 						language.descendantLang = &descTree.Lang
 
 						// Also add descendant tree as a descendant
-						desc := tpl.Descendant{Lang: descTree.Lang, Word: descTree.Word}
+						desc := tpl.Descendant(descTree)
 						language.Descendants = append(language.Descendants, desc)
+						language.addDescendant(&DescendantNode{
+							Lang:     descTree.Lang,
+							Word:     descTree.Word,
+							Relation: DescendantRelation(desc),
+							DescTree: true,
+						})
 					}
 				case "etymtree":
 					etymTree := template.ToEtymTree()
@@ -871,6 +1202,8 @@ Parse:
 							language.descendantLang = &etymTree.Lang
 						}
 					}
+				default:
+					diagnostics.Add(UnknownTemplate, language.Code, template.Action, name)
 				}
 			}
 			if definitionSection(language.section) {
@@ -878,59 +1211,62 @@ Parse:
 				case "l", "link":
 					link := template.ToLink()
 					if language.definitionBuffer != nil {
-						language.definitionBuffer = append(language.definitionBuffer, link.Text())
+						language.definitionBuffer.linkTo(link.Text(), link.Lang, link.Word)
 					}
 				case "m", "mention":
 					mention := template.ToMention()
 					if language.definitionBuffer != nil {
-						language.definitionBuffer = append(language.definitionBuffer, mention.Text())
+						language.definitionBuffer.mention(mention)
 					}
 				case "gloss":
 					gloss := template.ToGloss()
 					if language.definitionBuffer != nil {
-						language.definitionBuffer = append(language.definitionBuffer, gloss.Text())
+						language.definitionBuffer.typed(GlossSpan, gloss.Text())
 					}
 				case "non-gloss definition", "non-gloss", "non gloss", "ngd", "n-g":
 					nonGloss := template.ToNonGloss()
 					if language.definitionBuffer != nil {
-						language.definitionBuffer = append(language.definitionBuffer, nonGloss.Text())
+						language.definitionBuffer.typed(NonGlossSpan, nonGloss.Text())
 					}
 				case "label", "lbl", "lb":
 					label := template.ToLabel()
 					if language.definitionBuffer != nil {
-						language.definitionBuffer = append(language.definitionBuffer, label.Text())
+						language.definitionBuffer.typed(LabelSpan, label.Text())
+						for _, unknown := range label.UnknownLabels() {
+							diagnostics.Add(UnknownLabel, language.Code, unknown, name)
+						}
 					}
 				case "qualifier", "qual", "q", "i":
 					qualifier := template.ToQualifier()
 					if language.definitionBuffer != nil {
-						language.definitionBuffer = append(language.definitionBuffer, qualifier.Text())
+						language.definitionBuffer.typed(QualifierSpan, qualifier.Text())
 					}
 				case "frac":
 					frac := template.ToFrac()
 					if language.definitionBuffer != nil {
-						language.definitionBuffer = append(language.definitionBuffer, frac.Text())
+						language.definitionBuffer.text(frac.Text())
 					}
 
 					// Spanish forms
 				case "es-verb form of":
 					spanishVerb := template.ToSpanishVerb()
 					if language.definitionBuffer != nil {
-						language.definitionBuffer = append(language.definitionBuffer, spanishVerb.Text())
 						language.definitionRoot = &RootWord{Lang: spanishLang, Name: spanishVerb.Word}
+						language.definitionBuffer.textWithRoot(spanishVerb.Text(), language.definitionRoot)
 					}
 				case "es-compound of":
 					spanishCompound := template.ToSpanishCompound()
 					if language.definitionBuffer != nil {
-						language.definitionBuffer = append(language.definitionBuffer, spanishCompound.Text())
 						language.definitionRoot = &RootWord{Lang: spanishLang, Name: spanishCompound.Word()}
+						language.definitionBuffer.textWithRoot(spanishCompound.Text(), language.definitionRoot)
 					}
 
 				default:
 					if template.Action == "form of" {
 						formOf := template.ToFormOfGeneric()
 						if language.definitionBuffer != nil {
-							language.definitionBuffer = append(language.definitionBuffer, formOf.Text())
 							language.definitionRoot = &RootWord{Lang: formOf.Lang, Name: formOf.DisplayWord()}
+							language.definitionBuffer.textWithRoot(formOf.Text(), language.definitionRoot)
 						}
 					} else {
 						var formTpl *FormTemplate
@@ -951,42 +1287,19 @@ Parse:
 
 							formOf := template.ToFormOf(formTpl.Text, formTpl.Tags...)
 							if language.definitionBuffer != nil {
-								language.definitionBuffer = append(language.definitionBuffer, formOf.Text())
 								language.definitionRoot = &RootWord{Lang: formOf.Lang, Name: formOf.DisplayWord()}
+								language.definitionBuffer.textWithRoot(formOf.Text(), language.definitionRoot)
+							}
+						} else if language.inDefinition() {
+							if strings.HasSuffix(template.Action, " of") {
+								diagnostics.Add(UnknownFormOf, language.Code, template.Action, name)
+							} else {
+								diagnostics.Add(UnknownTemplate, language.Code, template.Action, name)
 							}
 						}
 					}
 				}
 			}
-		case itemAction:
-			// Don't support nested templates for now.
-			if template == nil || templateDepth != 1 {
-				break
-			}
-			template.Action = i.val
-		case itemParamDelim:
-			namedParam = nil
-		case itemParamText:
-
-			// Don't support nested templates for now.
-			if template == nil || templateDepth != 1 {
-				break
-			}
-
-			if namedParam != nil {
-				namedParam.Value = i.val
-				template.NamedParameters = append(template.NamedParameters,
-					*namedParam)
-				namedParam = nil
-			} else {
-				template.Parameters = append(template.Parameters, i.val)
-			}
-		case itemParamName:
-			// Don't support nested templates for now
-			if template == nil || templateDepth != 1 {
-				break
-			}
-			namedParam = &tpl.Parameter{Name: i.val}
 		}
 	}
 
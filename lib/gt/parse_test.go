@@ -9,99 +9,352 @@ import (
 	"github.com/vthommeret/glossterm/lib/tpl"
 )
 
-func TestParse(t *testing.T) {
-	tests := []struct {
-		desc string
-		word string
-		text string
-		want Word
-	}{
-		{
-			"Unstructured text",
-			"dictionary",
-			"definition",
-			Word{
-				Name: "dictionary",
+var parseTests = []struct {
+	desc string
+	word string
+	text string
+	want Word
+}{
+	{
+		"Unstructured text",
+		"dictionary",
+		"definition",
+		Word{
+			Name: "dictionary",
+		},
+	},
+	{
+		"Simple mention",
+		"dictionary",
+		"==English==\n\n===Etymology===\n{{m|la|dictio||speaking}}",
+		Word{
+			Name: "dictionary",
+			Languages: map[string]*Language{
+				"en": {
+					Code: "en",
+					Etymology: &Etymology{
+						Mentions: []tpl.Mention{
+							{Lang: "la", Word: "dictio", Gloss: "speaking"},
+						},
+					},
+				},
 			},
 		},
-		{
-			"Simple mention",
-			"dictionary",
-			"==English==\n\n===Etymology===\n{{m|la|dictio||speaking}}",
-			Word{
-				Name: "dictionary",
-				Languages: map[string]*Language{
-					"en": {
-						Code: "en",
-						Etymology: &Etymology{
-							Mentions: []tpl.Mention{
-								{Lang: "la", Word: "dictio", Gloss: "speaking"},
-							},
+	},
+	{
+		"Named parameter",
+		"dictionary",
+		"==English==\n\n===Etymology===\n{{m|la|dictio|t=speaking}}",
+		Word{
+			Name: "dictionary",
+			Languages: map[string]*Language{
+				"en": {
+					Code: "en",
+					Etymology: &Etymology{
+						Mentions: []tpl.Mention{
+							{Lang: "la", Word: "dictio", Gloss: "speaking"},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Nested templates (should be ignored)",
+		"dictionary",
+		"==English==\n\n===Etymology===\n{{m|la|dictio}}\n{{m|la|dictus{{m|la|dictus}}}}",
+		Word{
+			Name: "dictionary",
+			Languages: map[string]*Language{
+				"en": {
+					Code: "en",
+					Etymology: &Etymology{
+						Mentions: []tpl.Mention{
+							{Lang: "la", Word: "dictio"},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Language list",
+		"papyrus",
+		"==Latin==\n\n====Descendants====\n* English: {{l|en|papyrus}}, [[paper]]\n* French: {{l|fr|papyrus}}, {{l|fr|papier}}, [[Category:paper]]",
+		Word{
+			Name: "papyrus",
+			Languages: map[string]*Language{
+				"la": {
+					Code: "la",
+					Links: []tpl.Link{
+						{Lang: "en", Word: "papyrus"},
+						{Lang: "en", Word: "paper"},
+						{Lang: "fr", Word: "papyrus"},
+						{Lang: "fr", Word: "papier"},
+					},
+					DescendantTree: []*DescendantNode{
+						{Lang: "en", Word: "papyrus", Depth: 1},
+						{Lang: "en", Word: "paper", Depth: 1},
+						{Lang: "fr", Word: "papyrus", Depth: 1},
+						{Lang: "fr", Word: "papier", Depth: 1},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Nested descendants",
+		"frater",
+		"==Latin==\n\n====Descendants====\n* Old French: {{desc|fro|frere}}\n** Middle French:\n*** French: {{desc|fr|frère}}\n* Spanish: {{desc|es|fraile|bor=1}}\n* Portuguese: {{desctree|pt|frade|der=1}}",
+		Word{
+			Name: "frater",
+			Languages: map[string]*Language{
+				"la": {
+					Code: "la",
+					Descendants: []tpl.Descendant{
+						{Lang: "fro", Word: "frere"},
+						{Lang: "fr", Word: "frère"},
+						{Lang: "es", Word: "fraile", Borrowed: true},
+						{Lang: "pt", Word: "frade", Derived: true},
+					},
+					DescTrees: []tpl.DescTree{{Lang: "pt", Word: "frade", Derived: true}},
+					// French came through Middle French, which is listed
+					// without a word.
+					DescendantTree: []*DescendantNode{
+						{Lang: "fro", Word: "frere", Depth: 1, Relation: InheritedDescendant, Children: []*DescendantNode{
+							{Lang: "frm", Depth: 2, Children: []*DescendantNode{
+								{Lang: "fr", Word: "frère", Depth: 3, Relation: InheritedDescendant},
+							}},
+						}},
+						{Lang: "es", Word: "fraile", Depth: 1, Relation: BorrowedDescendant},
+						{Lang: "pt", Word: "frade", Depth: 1, Relation: DerivedDescendant, DescTree: true},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Descendant parameters",
+		"fraternitas",
+		"==Latin==\n\n====Descendants====\n* English: {{desc|en|fraternity|fraternitie|lbor=1|t=brotherhood|tr=-|q=archaic|unc=1}}\n* French: {{desc|fr|fraternité|clq=yes}}",
+		Word{
+			Name: "fraternitas",
+			Languages: map[string]*Language{
+				"la": {
+					Code: "la",
+					Descendants: []tpl.Descendant{
+						{
+							Lang:             "en",
+							Word:             "fraternity",
+							Terms:            []string{"fraternitie"},
+							Gloss:            "brotherhood",
+							Translit:         "-",
+							Qualifiers:       []string{"archaic"},
+							LearnedBorrowing: true,
+							Uncertain:        true,
 						},
+						{Lang: "fr", Word: "fraternité", Calque: true},
+					},
+					DescendantTree: []*DescendantNode{
+						{Lang: "en", Word: "fraternity", Depth: 1, Relation: BorrowedDescendant},
+						{Lang: "fr", Word: "fraternité", Depth: 1, Relation: BorrowedDescendant},
 					},
 				},
 			},
 		},
-		{
-			"Named parameter",
-			"dictionary",
-			"==English==\n\n===Etymology===\n{{m|la|dictio|t=speaking}}",
-			Word{
-				Name: "dictionary",
-				Languages: map[string]*Language{
-					"en": {
-						Code: "en",
-						Etymology: &Etymology{
-							Mentions: []tpl.Mention{
-								{Lang: "la", Word: "dictio", Gloss: "speaking"},
+	},
+	{
+		"Etymology relations",
+		"nacional",
+		"==Spanish==\n\n===Etymology===\n{{lbor|es|la|nātiōnālis}}, equivalent to {{af|es|nación|-al|t2=relating to}}. " +
+			"{{doublet|es|natal|nativo}}, {{clipping|es|nacionalista}}. {{cal|es|fr|national}}. {{root|es|ine-pro|*ǵenh₁-}}. " +
+			"Not related to {{noncog|en|nation}}.",
+		Word{
+			Name: "nacional",
+			Languages: map[string]*Language{
+				"es": {
+					Code: "es",
+					Etymology: &Etymology{
+						Loans: []tpl.Loan{
+							{Lang: "es", FromLang: "la", FromWord: "nationalis", Kind: tpl.LearnedBorrowing},
+							{Lang: "es", FromLang: "fr", FromWord: "national", Kind: tpl.Calque},
+						},
+						Affixes: []tpl.Affix{{
+							Lang:  "es",
+							Parts: []tpl.AffixPart{{Word: "nación"}, {Word: "-al", Gloss: "relating to"}},
+							Kind:  tpl.AffixKind,
+						}},
+						Related: []tpl.Related{
+							{Lang: "es", Words: []string{"natal", "nativo"}, Kind: tpl.Doublet},
+							{Lang: "es", Words: []string{"nacionalista"}, Kind: tpl.Clipping},
+						},
+						Roots:       []tpl.Root{{Lang: "es", RootLang: "ine-pro", Roots: []string{"*ǵenh₁-"}}},
+						NonCognates: []tpl.NonCognate{{Lang: "en", Word: "nation"}},
+						Morphology: []Morpheme{
+							{Form: "nación", Type: RootMorpheme, Lang: "es"},
+							{Form: "-al", Type: SuffixMorpheme, Lang: "es", Gloss: "relating to"},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Morphology",
+		"deshacer",
+		"==Spanish==\n\n===Etymology===\n{{prefix|es|des|hacer}}, like {{suffix|es|hacer|ción}} and {{confix|es|auto|móvil}}.",
+		Word{
+			Name: "deshacer",
+			Languages: map[string]*Language{
+				"es": {
+					Code: "es",
+					Etymology: &Etymology{
+						Prefixes: []tpl.Prefix{{Prefix: "des", Root: "hacer", Lang: "es"}},
+						Suffixes: []tpl.Suffix{{Lang: "es", Root: "hacer", Suffix: "ción"}},
+						Affixes: []tpl.Affix{{
+							Lang:  "es",
+							Parts: []tpl.AffixPart{{Word: "auto"}, {Word: "móvil"}},
+							Kind:  tpl.Confix,
+						}},
+						// hacer is only listed once.
+						Morphology: []Morpheme{
+							{Form: "des-", Type: PrefixMorpheme, Lang: "es"},
+							{Form: "hacer", Type: RootMorpheme, Lang: "es"},
+							{Form: "-ción", Type: SuffixMorpheme, Lang: "es"},
+							{Form: "auto-", Type: PrefixMorpheme, Lang: "es"},
+							{Form: "-móvil", Type: SuffixMorpheme, Lang: "es"},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Morphology with lang= and glosses",
+		"deshacer",
+		"==Spanish==\n\n===Etymology===\n{{prefix|des|hacer|lang=es|t1=reversal|t2=to do}}, like {{suffix|hacer|ción|lang=es|t2=action}} and {{prefix|es|-|hacer}}.",
+		Word{
+			Name: "deshacer",
+			Languages: map[string]*Language{
+				"es": {
+					Code: "es",
+					Etymology: &Etymology{
+						Prefixes: []tpl.Prefix{
+							{Prefix: "des", Root: "hacer", Lang: "es", PrefixGloss: "reversal", RootGloss: "to do"},
+							{Prefix: "-", Root: "hacer", Lang: "es"},
+						},
+						Suffixes: []tpl.Suffix{{Lang: "es", Root: "hacer", Suffix: "ción", SuffixGloss: "action"}},
+						// A bare hyphen isn't a morpheme.
+						Morphology: []Morpheme{
+							{Form: "des-", Type: PrefixMorpheme, Lang: "es", Gloss: "reversal"},
+							{Form: "hacer", Type: RootMorpheme, Lang: "es", Gloss: "to do"},
+							{Form: "-ción", Type: SuffixMorpheme, Lang: "es", Gloss: "action"},
+						},
+					},
+				},
+			},
+		},
+	},
+	{
+		"Comments and references in definitions",
+		"dictionary",
+		"==English==\n\n===Noun===\n# A [[book]]<!-- hidden --> {{gloss|of words}}<ref>{{cite-book|author=Smith|title=[[Words]]|year=1990}}</ref><ref>{{R:Smith|[[Words]]}}</ref>\n# A list",
+		Word{
+			Name: "dictionary",
+			Languages: map[string]*Language{
+				"en": {
+					Code: "en",
+					Definitions: &Definitions{
+						Nouns: []Definition{
+							{
+								Text: "A book (of words)",
+								Spans: []Span{
+									{Type: TextSpan, Text: "A "},
+									{Type: LinkSpan, Text: "book", Lang: "en", Word: "book"},
+									{Type: TextSpan, Text: " "},
+									{Type: GlossSpan, Text: "(of words)"},
+								},
+								References: []string{"Smith, Words, 1990", "{{R:Smith|Words}}"},
 							},
+							{Text: "A list", Spans: []Span{{Type: TextSpan, Text: "A list"}}},
 						},
 					},
 				},
 			},
 		},
-		{
-			"Nested templates (should be ignored)",
-			"dictionary",
-			"==English==\n\n===Etymology===\n{{m|la|dictio}}\n{{m|la|dictus{{m|la|dictus}}}}",
-			Word{
-				Name: "dictionary",
-				Languages: map[string]*Language{
-					"en": {
-						Code: "en",
-						Etymology: &Etymology{
-							Mentions: []tpl.Mention{
-								{Lang: "la", Word: "dictio"},
+	},
+	{
+		"Typed spans in definitions",
+		"libros",
+		"==Spanish==\n\n===Noun===\n# {{lb|es|archaic}} {{plural of|es|libro}}\n# {{q|rare}} a [[tome#English|tome]] or {{m|la|liber|t=bark}}",
+		Word{
+			Name: "libros",
+			Languages: map[string]*Language{
+				"es": {
+					Code: "es",
+					Definitions: &Definitions{
+						Nouns: []Definition{
+							{
+								Text: "(archaic) plural of libro",
+								Spans: []Span{
+									{Type: LabelSpan, Text: "(archaic)"},
+									{Type: TextSpan, Text: " plural of "},
+									{Type: LinkSpan, Text: "libro", Lang: "es", Word: "libro"},
+								},
+								Root: &RootWord{Lang: "es", Name: "libro"},
+							},
+							{
+								Text: "(rare) a tome or liber (bark)",
+								Spans: []Span{
+									{Type: QualifierSpan, Text: "(rare)"},
+									{Type: TextSpan, Text: " a "},
+									{Type: LinkSpan, Text: "tome", Lang: "en", Word: "tome"},
+									{Type: TextSpan, Text: " or "},
+									{Type: LinkSpan, Text: "liber", Lang: "la", Word: "liber"},
+									{Type: TextSpan, Text: " "},
+									{Type: GlossSpan, Text: "(bark)"},
+								},
 							},
 						},
 					},
 				},
 			},
 		},
-		{
-			"Language list",
-			"papyrus",
-			"==Latin==\n\n====Descendants====\n* English: {{l|en|papyrus}}, [[paper]]\n* French: {{l|fr|papyrus}}, {{l|fr|papier}}, [[Category:paper]]",
-			Word{
-				Name: "papyrus",
-				Languages: map[string]*Language{
-					"la": {
-						Code: "la",
-						Links: []tpl.Link{
-							{Lang: "en", Word: "papyrus"},
-							{Lang: "en", Word: "paper"},
-							{Lang: "fr", Word: "papyrus"},
-							{Lang: "fr", Word: "papier"},
+	},
+	{
+		"Formatting in definitions",
+		"dictionary",
+		"==English==\n\n===Noun===\n# A '''reference''' book&nbsp;of x<sup>2</sup> words<ref>From [[source|a source]]</ref>",
+		Word{
+			Name: "dictionary",
+			Languages: map[string]*Language{
+				"en": {
+					Code: "en",
+					Definitions: &Definitions{
+						Nouns: []Definition{
+							{
+								Text: "A reference book\u00a0of x2 words",
+								Spans: []Span{
+									{Type: TextSpan, Text: "A "},
+									{Type: TextSpan, Text: "reference", Bold: true},
+									{Type: TextSpan, Text: " book\u00a0of x"},
+									{Type: TextSpan, Text: "2", Superscript: true},
+									{Type: TextSpan, Text: " words"},
+								},
+								References: []string{"From a source"},
+							},
 						},
 					},
 				},
 			},
 		},
-	}
+	},
+}
 
+func TestParse(t *testing.T) {
 	ignoreUnexported := cmpopts.IgnoreUnexported(Language{})
 
-	for _, tt := range tests {
+	for _, tt := range parseTests {
 		got, err := ParseWord(Page{Title: tt.word, Text: tt.text}, lang.DefaultLangMap)
 		if err != nil {
 			t.Errorf("%s: gt.ParseWord(%q, %q) got error: %s.", tt.desc, tt.word, tt.text, err)
@@ -2,7 +2,6 @@ package gt
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/vthommeret/glossterm/lib/tpl"
 )
@@ -11,6 +10,8 @@ type Descendants struct {
 	Word        string
 	Links       []tpl.Link
 	Descendants []tpl.Descendant
+	// Descendants and links as nested in the list.
+	Tree []*DescendantNode `json:",omitempty"`
 }
 
 // Parses a given etymology tree (e.g. https://en.wiktionary.org/wiki/Template:etymtree/la/germanus)
@@ -19,21 +20,26 @@ func ParseEtymTree(p Page, langMap map[string]bool) (*Descendants, error) {
 		Word: p.Title,
 	}
 
-	var template *tpl.Template
-	var namedParam *tpl.Parameter
+	var templates templateBuffer
 	var listItem *ListItem
-
-	var depth int
+	var listItemDepth int
+	tree := descendantTreeBuilder{roots: &descendants.Tree}
 
 	l := NewLexer(p.Text)
+	defer l.Stop()
 
 Parse:
 	for {
 		i := l.NextItem()
 
+		if templates.depth > 0 && isTemplateItem(i.typ) {
+			templates.add(i)
+			continue
+		}
+
 		switch i.typ {
 		case itemError:
-			return nil, fmt.Errorf("unable to parse: %s", i.val)
+			return nil, l.errorAt(p.Title, LexError, i, fmt.Sprintf("unable to lex: %s", i.val))
 		case itemEOF:
 			if listItem != nil {
 				descendants.Links =
@@ -42,6 +48,8 @@ Parse:
 			break Parse
 		case itemUnorderedListItemStart:
 			listItem = &ListItem{}
+			listItemDepth = i.depth
+			tree.startItem(i.depth)
 		case itemListItemPrefix:
 			if listItem != nil {
 				listItem.Prefix = i.val
@@ -50,24 +58,19 @@ Parse:
 			if listItem != nil {
 				listItem.Links = append(listItem.Links, i.val)
 			}
-		case itemText:
-			// TODO: More intelligently handle whitespace in lexer. Emit newline
-			// tokens and ignore otherwise unimportant whitespace.
-			if listItem != nil && strings.Contains(i.val, "\n") {
+		case itemListItemEnd:
+			tree.endItem()
+			if listItem != nil {
 				descendants.Links =
 					append(descendants.Links, listItem.TplLinks(langMap, p.Title)...)
 				listItem = nil
 			}
+		case itemText:
+			tree.text(i.val, langMap)
 		case itemLeftTemplate:
-			depth++
-			if depth == 1 {
-				template = &tpl.Template{}
-			} else {
-				// Nested templates aren't supported for now.
-				template = nil
-			}
+			templates.open()
 		case itemRightTemplate:
-			depth--
+			template := templates.close()
 			if template == nil {
 				break
 			}
@@ -76,35 +79,19 @@ Parse:
 				desc := template.ToDescendant()
 				if _, ok := langMap[desc.Lang]; ok {
 					descendants.Descendants = append(descendants.Descendants, desc)
+					tree.add(listItemDepth, &DescendantNode{
+						Lang:     desc.Lang,
+						Word:     desc.Word,
+						Relation: DescendantRelation(desc),
+					})
 				}
 			case "l", "link":
 				link := template.ToLink()
 				if _, ok := langMap[link.Lang]; ok {
 					descendants.Links = append(descendants.Links, link)
+					tree.add(listItemDepth, &DescendantNode{Lang: link.Lang, Word: link.Word})
 				}
 			}
-		case itemAction:
-			if template == nil {
-				break
-			}
-			template.Action = i.val
-		case itemParamText:
-			if template == nil {
-				break
-			}
-			if namedParam != nil {
-				namedParam.Value = i.val
-				template.NamedParameters = append(template.NamedParameters,
-					*namedParam)
-				namedParam = nil
-			} else {
-				template.Parameters = append(template.Parameters, i.val)
-			}
-		case itemParamName:
-			if template == nil {
-				break
-			}
-			namedParam = &tpl.Parameter{Name: i.val}
 		}
 	}
 
@@ -1,9 +1,14 @@
 package gt
 
 import (
+	"crypto/sha1"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/vthommeret/glossterm/lib/lang"
@@ -20,23 +25,19 @@ type Page struct {
 	Text    string   `xml:"revision>text"`
 }
 
-type Error struct {
-	Message string
-	Fatal   bool
-}
-
 const count = 1
 const etymTree = "Template:etymtree/"
 
-// ParseXMLPage returns page for cmd/gtpage.
-func ParseXMLPage(r io.ReadCloser, title string, page chan<- Page, errors chan<- Error, done chan<- io.ReadCloser) {
+// ParseXMLPage returns the pages with the given titles for cmd/gtpage.
+func ParseXMLPage(r io.ReadCloser, titles map[string]bool, page chan<- Page, errors chan<- Error, done chan<- io.ReadCloser) {
 	d := xml.NewDecoder(r)
+	found := 0
 Parse:
 	for {
 		t, err := d.Token()
 		if err != nil {
 			if err != io.EOF {
-				errors <- Error{fmt.Sprintf("unable to decode token: %s", err), true}
+				errors <- Error{Message: fmt.Sprintf("unable to decode token: %s", err), Category: XMLError, Severity: FatalSeverity}
 			}
 			break
 		}
@@ -45,9 +46,12 @@ Parse:
 			if se.Name.Local == "page" {
 				var p Page
 				d.DecodeElement(&p, &se)
-				if p.Title == title {
+				if titles[p.Title] {
 					page <- p
-					break Parse
+					found++
+					if found == len(titles) {
+						break Parse
+					}
 				}
 			}
 		}
@@ -64,7 +68,7 @@ Parse:
 		t, err := d.Token()
 		if err != nil {
 			if err != io.EOF {
-				errors <- Error{fmt.Sprintf("unable to decode token: %s", err), true}
+				errors <- Error{Message: fmt.Sprintf("unable to decode token: %s", err), Category: XMLError, Severity: FatalSeverity}
 			}
 			break
 		}
@@ -85,8 +89,20 @@ Parse:
 	done <- r
 }
 
-// ParseXMLWords returns words and descendants for cmd/gtparse.
-func ParseXMLWords(r io.ReadCloser, words chan<- Word, descendants chan<- Descendants, errors chan<- Error, done chan<- io.ReadCloser) {
+// ParseOptions configures ParseXMLWords.
+type ParseOptions struct {
+	// Diagnostics counts what isn't handled, if set.
+	Diagnostics *Diagnostics
+
+	// QuarantineDir is where pages the lexer or parser panics on are written
+	// as XML, if set, so they can be replayed with cmd/gtparseword.
+	QuarantineDir string
+}
+
+// ParseXMLWords returns words and descendants for cmd/gtparse. A page that
+// fails to parse, including one that panics, is reported as an error and
+// skipped.
+func ParseXMLWords(r io.ReadCloser, words chan<- Word, descendants chan<- Descendants, errors chan<- Error, done chan<- io.ReadCloser, opts ParseOptions) {
 	d := xml.NewDecoder(r)
 
 Parse:
@@ -94,7 +110,7 @@ Parse:
 		t, err := d.Token()
 		if err != nil {
 			if err != io.EOF {
-				errors <- Error{fmt.Sprintf("unable to decode token: %s", err), true}
+				errors <- Error{Message: fmt.Sprintf("unable to decode token: %s", err), Category: XMLError, Severity: FatalSeverity}
 			}
 			break
 		}
@@ -103,22 +119,23 @@ Parse:
 			if se.Name.Local == "page" {
 				var p Page
 				d.DecodeElement(&p, &se)
-				if strings.HasPrefix(p.Title, etymTree) {
-					ds, err := ParseEtymTree(p, lang.DefaultLangMap)
-					if err != nil {
-						errors <- Error{fmt.Sprintf("unable to parse %q word: %s", p.Title, err), false}
-						continue Parse
+				w, ds, err := parsePage(p, opts.Diagnostics)
+				if err != nil {
+					e := pageError(p.Title, err)
+					if (e.Category == LexError || e.Category == PanicError) && opts.QuarantineDir != "" {
+						if path, err := QuarantinePage(opts.QuarantineDir, p); err != nil {
+							e.Message = fmt.Sprintf("%s (unable to quarantine page: %s)", e.Message, err)
+						} else {
+							e.Quarantined = path
+						}
 					}
+					errors <- e
+					continue Parse
+				}
+				if ds != nil {
 					descendants <- *ds
-				} else {
-					w, err := ParseWord(p, lang.DefaultLangMap)
-					if err != nil {
-						errors <- Error{fmt.Sprintf("unable to parse %q word: %s", p.Title, err), false}
-						continue Parse
-					} else if w.IsEmpty() {
-						continue Parse
-					}
-					words <- w
+				} else if w != nil && !w.IsEmpty() {
+					words <- *w
 				}
 			}
 		}
@@ -126,3 +143,85 @@ Parse:
 
 	done <- r
 }
+
+// Page parsers, replaced in tests.
+var (
+	parseEtymTree = ParseEtymTree
+	parseWord     = ParseWordDiagnostics
+)
+
+// parsePage parses an etymtree page or a word, recovering from panics.
+func parsePage(p Page, diagnostics *Diagnostics) (w *Word, ds *Descendants, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			w, ds, err = nil, nil, panicError(p.Title, r)
+		}
+	}()
+
+	if strings.HasPrefix(p.Title, etymTree) {
+		ds, err = parseEtymTree(p, lang.DefaultLangMap)
+		return nil, ds, err
+	}
+
+	word, err := parseWord(p, lang.DefaultLangMap, diagnostics)
+	if err != nil {
+		return nil, nil, err
+	}
+	word.Digest, err = parseDigest(word)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &word, nil, nil
+}
+
+// parseDigest returns a hash of a parsed word. JSON is hashed rather than a
+// gob since map keys are sorted.
+func parseDigest(w Word) (string, error) {
+	b, err := json.Marshal(w)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha1.Sum(b)), nil
+}
+
+// QuarantinePage writes a page as XML to dir, returning its path.
+func QuarantinePage(dir string, p Page) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, PageFilename(p.Title)+".xml")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := WritePageXML(f, p); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// PageFilename returns a title escaped for use as a filename on any platform,
+// e.g. Template%3Aetymtree%2Fla%2Fgermanus.
+func PageFilename(title string) string {
+	return strings.Replace(url.PathEscape(title), ":", "%3A", -1)
+}
+
+var xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;", "\r", "&#xD;")
+
+// WritePageXML writes a page in the same format as the dump. Unlike
+// xml.Encoder, newlines are kept as is so the text stays readable.
+func WritePageXML(w io.Writer, p Page) error {
+	var b strings.Builder
+	b.WriteString("<page>\n")
+	fmt.Fprintf(&b, "  <title>%s</title>\n", xmlTextEscaper.Replace(p.Title))
+	if p.Redir.Title != "" {
+		fmt.Fprintf(&b, "  <redirect title=\"%s\" />\n", xmlTextEscaper.Replace(p.Redir.Title))
+	}
+	b.WriteString("  <revision>\n")
+	fmt.Fprintf(&b, "    <text xml:space=\"preserve\">%s</text>\n", xmlTextEscaper.Replace(p.Text))
+	b.WriteString("  </revision>\n")
+	b.WriteString("</page>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,68 @@
+package gt
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQuarantinePage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := Page{Title: "Template:etymtree/la/germanus", Text: "==Latin==\n{{m|la|germanus}} & <ref>x</ref>"}
+
+	path, err := QuarantinePage(dir, p)
+	if err != nil {
+		t.Fatalf("gt.QuarantinePage(%q) got error: %s.", p.Title, err)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("gt.QuarantinePage(%q) wrote %s outside of %s.", p.Title, path, dir)
+	}
+
+	// Read the page back the same way cmd/gtparseword does.
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Unable to open %s: %s", path, err)
+	}
+	defer f.Close()
+	var got Page
+	if err := xml.NewDecoder(f).Decode(&got); err != nil {
+		t.Fatalf("Unable to decode %s: %s", path, err)
+	}
+	got.XMLName = xml.Name{}
+	if diff := cmp.Diff(p, got); diff != "" {
+		t.Errorf("gt.QuarantinePage(%q) diff: %s", p.Title, diff)
+	}
+}
+
+func TestParsePagePanic(t *testing.T) {
+	origWord, origEtymTree := parseWord, parseEtymTree
+	defer func() {
+		parseWord, parseEtymTree = origWord, origEtymTree
+	}()
+	parseWord = func(Page, map[string]bool, *Diagnostics) (Word, error) {
+		panic("word")
+	}
+	parseEtymTree = func(Page, map[string]bool) (*Descendants, error) {
+		panic("etymtree")
+	}
+
+	for _, p := range []Page{
+		{Title: "word", Text: "==English=="},
+		{Title: "Template:etymtree/la/germanus", Text: "* Latin: {{l|la|germanus}}"},
+	} {
+		w, ds, err := parsePage(p, NewDiagnostics())
+		e, ok := err.(Error)
+		if w != nil || ds != nil || !ok || e.Category != PanicError || e.Title != p.Title || e.Stack == "" {
+			t.Errorf("parsePage(%q) got %#v, want panic error.", p.Title, err)
+		}
+	}
+}
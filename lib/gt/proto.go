@@ -0,0 +1,368 @@
+package gt
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vthommeret/glossterm/lib/tpl"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Encoding of words as protocol buffers with the schema in proto/gt.proto and
+// proto/tpl.proto. Field numbers here must match the schema.
+
+// MarshalProto returns the word encoded as a glossterm.gt.Word message.
+func (w *Word) MarshalProto() []byte {
+	var b []byte
+	b = appendProtoString(b, 1, w.Name)
+
+	// Sort languages so output is stable.
+	var codes []string
+	for code := range w.Languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		var entry []byte
+		entry = appendProtoString(entry, 1, code)
+		entry = appendProtoMessage(entry, 2, protoLanguage(w.Languages[code]))
+		b = appendProtoMessage(b, 2, entry)
+	}
+
+	if w.Indexed != nil {
+		b = appendProtoMessage(b, 3, protoTimestamp(*w.Indexed))
+	}
+	return b
+}
+
+// WriteProto writes the word as a glossterm.gt.Word message prefixed with its
+// length as a varint.
+func WriteProto(out io.Writer, w *Word) error {
+	m := w.MarshalProto()
+	b := protowire.AppendVarint(nil, uint64(len(m)))
+	_, err := out.Write(append(b, m...))
+	return err
+}
+
+// WriteJSONL writes the word as JSON on a single line.
+func WriteJSONL(out io.Writer, w *Word) error {
+	b, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(b, '\n'))
+	return err
+}
+
+func protoTimestamp(t time.Time) []byte {
+	var b []byte
+	if s := t.Unix(); s != 0 {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(s))
+	}
+	if n := t.Nanosecond(); n != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(n))
+	}
+	return b
+}
+
+func protoLanguage(l *Language) []byte {
+	var b []byte
+	if l == nil {
+		return b
+	}
+	b = appendProtoString(b, 1, l.Code)
+	if l.Definitions != nil {
+		b = appendProtoMessage(b, 2, protoDefinitions(l.Definitions))
+	}
+	if l.Etymology != nil {
+		b = appendProtoMessage(b, 3, protoEtymology(l.Etymology))
+	}
+	for _, link := range l.Links {
+		b = appendProtoMessage(b, 4, protoLink(link))
+	}
+	for _, d := range l.Descendants {
+		b = appendProtoMessage(b, 5, protoDescendant(d))
+	}
+	for _, t := range l.DescendantTrees {
+		var m []byte
+		m = appendProtoString(m, 1, t.Lang)
+		m = appendProtoString(m, 2, t.RootLang)
+		m = appendProtoString(m, 3, t.Word)
+		b = appendProtoMessage(b, 6, m)
+	}
+	for _, t := range l.DescTrees {
+		b = appendProtoMessage(b, 7, protoDescendant(tpl.Descendant(t)))
+	}
+	for _, c := range l.Cognates {
+		var m []byte
+		m = appendProtoString(m, 1, c.Word)
+		m = appendProtoString(m, 2, c.From)
+		for _, e := range c.Path {
+			var em []byte
+			em = appendProtoString(em, 1, e.From)
+			em = appendProtoString(em, 2, e.Predicate)
+			em = appendProtoString(em, 3, e.To)
+			m = appendProtoMessage(m, 3, em)
+		}
+		m = appendProtoDouble(m, 4, c.Score)
+		m = appendProtoBool(m, 5, c.Weak)
+		b = appendProtoMessage(b, 8, m)
+	}
+	for _, n := range l.DescendantTree {
+		b = appendProtoMessage(b, 9, protoDescendantNode(n))
+	}
+	return b
+}
+
+func protoDescendantNode(n *DescendantNode) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, n.Lang)
+	b = appendProtoString(b, 2, n.Word)
+	b = appendProtoInt(b, 3, n.Depth)
+	b = appendProtoBool(b, 4, n.Cycle)
+	for _, c := range n.Children {
+		b = appendProtoMessage(b, 5, protoDescendantNode(c))
+	}
+	b = appendProtoString(b, 6, n.Relation)
+	b = appendProtoBool(b, 7, n.DescTree)
+	return b
+}
+
+func protoEtymology(e *Etymology) []byte {
+	var b []byte
+	for _, c := range e.Cognates {
+		b = appendProtoMessage(b, 1, protoLangWord(c.Lang, c.Word))
+	}
+	for _, m := range e.Mentions {
+		b = appendProtoMessage(b, 2, protoLink(tpl.Link(m)))
+	}
+	for _, t := range e.Borrows {
+		b = appendProtoMessage(b, 3, protoFrom(t.Lang, t.FromLang, t.FromWord, t.Alt, t.Gloss, t.PartOfSpeech, t.Literal))
+	}
+	for _, t := range e.Derived {
+		b = appendProtoMessage(b, 4, protoFrom(t.Lang, t.FromLang, t.FromWord, t.Alt, t.Gloss, t.PartOfSpeech, t.Literal))
+	}
+	for _, t := range e.Inherited {
+		b = appendProtoMessage(b, 5, protoFrom(t.Lang, t.FromLang, t.FromWord, t.Alt, t.Gloss, t.PartOfSpeech, t.Literal))
+	}
+	for _, p := range e.Prefixes {
+		var m []byte
+		m = appendProtoString(m, 1, p.Prefix)
+		m = appendProtoString(m, 2, p.Root)
+		m = appendProtoString(m, 3, p.Lang)
+		m = appendProtoString(m, 4, p.PrefixGloss)
+		m = appendProtoString(m, 5, p.RootGloss)
+		b = appendProtoMessage(b, 6, m)
+	}
+	for _, s := range e.Suffixes {
+		var m []byte
+		m = appendProtoString(m, 1, s.Lang)
+		m = appendProtoString(m, 2, s.Root)
+		m = appendProtoString(m, 3, s.Suffix)
+		m = appendProtoString(m, 4, s.RootGloss)
+		m = appendProtoString(m, 5, s.SuffixGloss)
+		b = appendProtoMessage(b, 7, m)
+	}
+	for _, link := range e.Links {
+		b = appendProtoMessage(b, 8, protoLink(link))
+	}
+	for _, l := range e.Loans {
+		m := protoFrom(l.Lang, l.FromLang, l.FromWord, l.Alt, l.Gloss, l.PartOfSpeech, l.Literal)
+		m = appendProtoString(m, 8, l.Kind)
+		b = appendProtoMessage(b, 9, m)
+	}
+	for _, a := range e.Affixes {
+		var m []byte
+		m = appendProtoString(m, 1, a.Lang)
+		for _, p := range a.Parts {
+			var pm []byte
+			pm = appendProtoString(pm, 1, p.Word)
+			pm = appendProtoString(pm, 2, p.Lang)
+			pm = appendProtoString(pm, 3, p.Alt)
+			pm = appendProtoString(pm, 4, p.Gloss)
+			pm = appendProtoString(pm, 5, p.PartOfSpeech)
+			m = appendProtoMessage(m, 2, pm)
+		}
+		m = appendProtoString(m, 3, a.Kind)
+		b = appendProtoMessage(b, 10, m)
+	}
+	for _, r := range e.Related {
+		var m []byte
+		m = appendProtoString(m, 1, r.Lang)
+		for _, w := range r.Words {
+			m = appendProtoString(m, 2, w)
+		}
+		m = appendProtoString(m, 3, r.Alt)
+		m = appendProtoString(m, 4, r.Gloss)
+		m = appendProtoString(m, 5, r.Kind)
+		b = appendProtoMessage(b, 11, m)
+	}
+	for _, r := range e.Roots {
+		var m []byte
+		m = appendProtoString(m, 1, r.Lang)
+		m = appendProtoString(m, 2, r.RootLang)
+		for _, root := range r.Roots {
+			m = appendProtoString(m, 3, root)
+		}
+		b = appendProtoMessage(b, 12, m)
+	}
+	for _, n := range e.NonCognates {
+		var m []byte
+		m = appendProtoString(m, 1, n.Lang)
+		m = appendProtoString(m, 2, n.Word)
+		m = appendProtoString(m, 3, n.Alt)
+		m = appendProtoString(m, 4, n.Gloss)
+		b = appendProtoMessage(b, 13, m)
+	}
+	for _, mo := range e.Morphology {
+		var m []byte
+		m = appendProtoString(m, 1, mo.Form)
+		m = appendProtoString(m, 2, mo.Type)
+		m = appendProtoString(m, 3, mo.Lang)
+		m = appendProtoString(m, 4, mo.Gloss)
+		b = appendProtoMessage(b, 14, m)
+	}
+	return b
+}
+
+func protoDefinitions(d *Definitions) []byte {
+	var b []byte
+	l := Language{Definitions: d}
+	for i, defs := range l.AllDefinitions() {
+		for _, def := range defs {
+			b = appendProtoMessage(b, protowire.Number(i+1), protoDefinition(def))
+		}
+	}
+	return b
+}
+
+func protoDefinition(d Definition) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, d.Text)
+	for _, s := range d.Spans {
+		var m []byte
+		m = appendProtoString(m, 1, string(s.Type))
+		m = appendProtoString(m, 2, s.Text)
+		m = appendProtoString(m, 3, s.Lang)
+		m = appendProtoString(m, 4, s.Word)
+		m = appendProtoBool(m, 5, s.Bold)
+		m = appendProtoBool(m, 6, s.Italic)
+		m = appendProtoBool(m, 7, s.Superscript)
+		m = appendProtoBool(m, 8, s.Subscript)
+		b = appendProtoMessage(b, 2, m)
+	}
+	if d.Root != nil {
+		var m []byte
+		m = appendProtoString(m, 1, d.Root.Lang)
+		m = appendProtoString(m, 2, d.Root.Name)
+		b = appendProtoMessage(b, 3, m)
+	}
+	for _, r := range d.References {
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendString(b, r)
+	}
+	return b
+}
+
+// protoLink encodes glossterm.tpl.Link and glossterm.tpl.Mention, which have
+// the same fields.
+func protoLink(l tpl.Link) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, l.Lang)
+	b = appendProtoString(b, 2, l.Word)
+	b = appendProtoString(b, 3, l.Alt)
+	b = appendProtoString(b, 4, l.Gloss)
+	b = appendProtoString(b, 5, l.PartOfSpeech)
+	b = appendProtoString(b, 6, l.Literal)
+	return b
+}
+
+// protoDescendant encodes glossterm.tpl.Descendant and DescTree.
+func protoDescendant(d tpl.Descendant) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, d.Lang)
+	b = appendProtoString(b, 2, d.Word)
+	for _, t := range d.Terms {
+		b = appendProtoString(b, 3, t)
+	}
+	b = appendProtoString(b, 4, d.Alt)
+	b = appendProtoString(b, 5, d.Gloss)
+	b = appendProtoString(b, 6, d.Translit)
+	for _, q := range d.Qualifiers {
+		b = appendProtoString(b, 7, q)
+	}
+	b = appendProtoBool(b, 8, d.Borrowed)
+	b = appendProtoBool(b, 9, d.LearnedBorrowing)
+	b = appendProtoBool(b, 10, d.SemiLearned)
+	b = appendProtoBool(b, 11, d.Calque)
+	b = appendProtoBool(b, 12, d.PartialCalque)
+	b = appendProtoBool(b, 13, d.SemanticLoan)
+	b = appendProtoBool(b, 14, d.Transliterated)
+	b = appendProtoBool(b, 15, d.Derived)
+	b = appendProtoBool(b, 16, d.Uncertain)
+	return b
+}
+
+// protoLangWord encodes messages with only a lang and word.
+func protoLangWord(lang, word string) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, lang)
+	b = appendProtoString(b, 2, word)
+	return b
+}
+
+// protoFrom encodes glossterm.tpl.Borrow, Derived and Inherited.
+func protoFrom(lang, fromLang, fromWord, alt, gloss, pos, literal string) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, lang)
+	b = appendProtoString(b, 2, fromLang)
+	b = appendProtoString(b, 3, fromWord)
+	b = appendProtoString(b, 4, alt)
+	b = appendProtoString(b, 5, gloss)
+	b = appendProtoString(b, 6, pos)
+	b = appendProtoString(b, 7, literal)
+	return b
+}
+
+// appendProtoString omits empty strings, like proto3.
+func appendProtoString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendProtoBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, 1)
+}
+
+func appendProtoInt(b []byte, num protowire.Number, v int) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendProtoDouble(b []byte, num protowire.Number, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func appendProtoMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
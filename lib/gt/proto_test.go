@@ -0,0 +1,505 @@
+package gt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/vthommeret/glossterm/lib/tpl"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var exportWord = Word{
+	Name: "libros",
+	Languages: map[string]*Language{
+		"es": {
+			Code: "es",
+			Definitions: &Definitions{
+				Nouns: []Definition{
+					{
+						Text: "plural of libro",
+						Spans: []Span{
+							{Type: TextSpan, Text: "plural of "},
+							{Type: LinkSpan, Text: "libro", Lang: "es", Word: "libro", Italic: true},
+						},
+						Root: &RootWord{Lang: "es", Name: "libro"},
+					},
+				},
+			},
+			Etymology: &Etymology{
+				Inherited: []tpl.Inherited{{Lang: "es", FromLang: "la", FromWord: "liber"}},
+			},
+		},
+	},
+}
+
+// protoFields decodes a message into the raw values of each field.
+func protoFields(t *testing.T, b []byte) map[protowire.Number][][]byte {
+	fields := map[protowire.Number][][]byte{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("Unable to consume tag: %s", protowire.ParseError(n))
+		}
+		b = b[n:]
+		var v []byte
+		switch typ {
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			var x uint64
+			x, n = protowire.ConsumeVarint(b)
+			v = protowire.AppendVarint(nil, x)
+		default:
+			t.Fatalf("Unexpected wire type %d for field %d.", typ, num)
+		}
+		if n < 0 {
+			t.Fatalf("Unable to consume field %d: %s", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		fields[num] = append(fields[num], v)
+	}
+	return fields
+}
+
+func TestWriteProto(t *testing.T) {
+	indexed := time.Unix(1580472000, 5)
+	w := exportWord
+	w.Indexed = &indexed
+
+	var buf bytes.Buffer
+	for i := 0; i < 2; i++ {
+		if err := WriteProto(&buf, &w); err != nil {
+			t.Fatalf("gt.WriteProto(%q) got error: %s.", w.Name, err)
+		}
+	}
+
+	// Both messages are length-delimited.
+	b := buf.Bytes()
+	for i := 0; i < 2; i++ {
+		m, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			t.Fatalf("Unable to read message %d: %s", i, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		word := protoFields(t, m)
+		if got := string(word[1][0]); got != w.Name {
+			t.Errorf("Word.name got %q, want %q.", got, w.Name)
+		}
+		if len(word[3]) != 1 {
+			t.Errorf("Word.indexed got %d values, want 1.", len(word[3]))
+		}
+
+		entry := protoFields(t, word[2][0])
+		if got := string(entry[1][0]); got != "es" {
+			t.Errorf("Word.languages key got %q, want %q.", got, "es")
+		}
+		language := protoFields(t, entry[2][0])
+		definitions := protoFields(t, language[2][0])
+		nouns := definitions[1]
+		if len(nouns) != 1 {
+			t.Fatalf("Definitions.nouns got %d values, want 1.", len(nouns))
+		}
+		definition := protoFields(t, nouns[0])
+		if got := string(definition[1][0]); got != "plural of libro" {
+			t.Errorf("Definition.text got %q, want %q.", got, "plural of libro")
+		}
+		if got := len(definition[2]); got != 2 {
+			t.Errorf("Definition.spans got %d values, want 2.", got)
+		}
+		etymology := protoFields(t, language[3][0])
+		inherited := protoFields(t, etymology[5][0])
+		if got := string(inherited[3][0]); got != "liber" {
+			t.Errorf("Inherited.from_word got %q, want %q.", got, "liber")
+		}
+	}
+	if len(b) != 0 {
+		t.Errorf("gt.WriteProto(%q) wrote %d extra bytes.", w.Name, len(b))
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	for i := 0; i < 2; i++ {
+		if err := WriteJSONL(&buf, &exportWord); err != nil {
+			t.Fatalf("gt.WriteJSONL(%q) got error: %s.", exportWord.Name, err)
+		}
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("gt.WriteJSONL(%q) wrote %d lines, want 2.", exportWord.Name, len(lines))
+	}
+	for _, line := range lines {
+		var got Word
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("Unable to decode %q: %s", line, err)
+		}
+		if diff := cmp.Diff(exportWord, got, cmpopts.IgnoreUnexported(Language{})); diff != "" {
+			t.Errorf("gt.WriteJSONL(%q) diff: %s", exportWord.Name, diff)
+		}
+	}
+}
+
+// protoOmitted are fields of Go types that aren't in the schema.
+var protoOmitted = map[string]bool{
+	"Word.Digest": true,
+}
+
+// TestProtoSchema checks MarshalProto against proto/gt.proto and
+// proto/tpl.proto by decoding a word with every field set using descriptors
+// built from the schema, and comparing each field to the word.
+func TestProtoSchema(t *testing.T) {
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(timestamppb.File_google_protobuf_timestamp_proto); err != nil {
+		t.Fatalf("Unable to register timestamp.proto: %s", err)
+	}
+	var gtFile protoreflect.FileDescriptor
+	for _, name := range []string{"tpl.proto", "gt.proto"} {
+		path := filepath.Join("..", "..", "proto", name)
+		fdp, err := parseProtoFile(path)
+		if err != nil {
+			t.Fatalf("Unable to parse %s: %s", path, err)
+		}
+		fd, err := protodesc.NewFile(fdp, files)
+		if err != nil {
+			t.Fatalf("Unable to build descriptor for %s: %s", path, err)
+		}
+		if err := files.RegisterFile(fd); err != nil {
+			t.Fatalf("Unable to register %s: %s", path, err)
+		}
+		gtFile = fd
+	}
+
+	var w Word
+	fillProtoValue(reflect.ValueOf(&w).Elem(), new(int), 0)
+	w.Digest = ""
+
+	m := dynamicpb.NewMessage(gtFile.Messages().ByName("Word"))
+	if err := proto.Unmarshal(w.MarshalProto(), m); err != nil {
+		t.Fatalf("Unable to decode word: %s", err)
+	}
+
+	c := protoChecker{t: t, set: map[protoreflect.FullName]bool{}, messages: map[protoreflect.FullName]protoreflect.MessageDescriptor{}}
+	c.message("Word", m, reflect.ValueOf(w))
+	for _, md := range c.messages {
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			if fd := fields.Get(i); !c.set[fd.FullName()] {
+				t.Errorf("Word.MarshalProto() never sets %s.", fd.FullName())
+			}
+		}
+	}
+}
+
+// fillProtoValue sets every exported field reachable from v to a distinct
+// value, so fields encoded with the wrong number don't compare equal. Lists
+// and nested lists stop a few levels deep.
+func fillProtoValue(v reflect.Value, n *int, depth int) {
+	*n++
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(fmt.Sprintf("s%d", *n))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(*n))
+	case reflect.Float64:
+		v.SetFloat(float64(*n) + 0.5)
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillProtoValue(v.Elem(), n, depth)
+	case reflect.Slice:
+		if depth < 3 {
+			v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+			fillProtoValue(v.Index(0), n, depth+1)
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		e := reflect.New(v.Type().Elem()).Elem()
+		fillProtoValue(e, n, depth+1)
+		v.SetMapIndex(reflect.ValueOf(fmt.Sprintf("k%d", *n)), e)
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Unix(int64(*n), int64(*n)).UTC()))
+			break
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				fillProtoValue(v.Field(i), n, depth)
+			}
+		}
+	}
+}
+
+// protoChecker compares decoded messages to the Go values they encode,
+// keeping track of the fields that were set.
+type protoChecker struct {
+	t        *testing.T
+	set      map[protoreflect.FullName]bool
+	messages map[protoreflect.FullName]protoreflect.MessageDescriptor
+}
+
+func (c *protoChecker) message(path string, m protoreflect.Message, v reflect.Value) {
+	md := m.Descriptor()
+	c.messages[md.FullName()] = md
+	if len(m.GetUnknown()) > 0 {
+		c.t.Errorf("%s has fields that aren't in %s.", path, md.FullName())
+	}
+
+	fields := map[string]bool{}
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		name := protoGoName(fd.Name())
+		fields[name] = true
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			c.t.Errorf("%s has no field %s for %s.", v.Type(), name, fd.FullName())
+			continue
+		}
+		if m.Has(fd) {
+			c.set[fd.FullName()] = true
+		}
+		c.field(path+"."+name, fd, m.Get(fd), f)
+	}
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if sf.PkgPath == "" && !fields[sf.Name] && !protoOmitted[v.Type().Name()+"."+sf.Name] {
+			c.t.Errorf("%s has no field for %s.%s.", md.FullName(), v.Type(), sf.Name)
+		}
+	}
+}
+
+func (c *protoChecker) field(path string, fd protoreflect.FieldDescriptor, pv protoreflect.Value, v reflect.Value) {
+	switch {
+	case fd.IsMap():
+		if pv.Map().Len() != v.Len() {
+			c.t.Errorf("%s got %d entries, want %d.", path, pv.Map().Len(), v.Len())
+		}
+		for _, k := range v.MapKeys() {
+			e := pv.Map().Get(protoreflect.ValueOfString(k.String()).MapKey())
+			c.value(fmt.Sprintf("%s[%q]", path, k), fd.MapValue(), e, v.MapIndex(k))
+		}
+	case fd.IsList():
+		if pv.List().Len() != v.Len() {
+			c.t.Errorf("%s got %d items, want %d.", path, pv.List().Len(), v.Len())
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			c.value(fmt.Sprintf("%s[%d]", path, i), fd, pv.List().Get(i), v.Index(i))
+		}
+	default:
+		c.value(path, fd, pv, v)
+	}
+}
+
+func (c *protoChecker) value(path string, fd protoreflect.FieldDescriptor, pv protoreflect.Value, v reflect.Value) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	var got, want interface{}
+	switch fd.Kind() {
+	case protoreflect.MessageKind:
+		if fd.Message().FullName() == "google.protobuf.Timestamp" {
+			fields := fd.Message().Fields()
+			ts := time.Unix(pv.Message().Get(fields.ByName("seconds")).Int(), pv.Message().Get(fields.ByName("nanos")).Int())
+			got, want = ts.UTC(), v.Interface().(time.Time).UTC()
+			break
+		}
+		c.message(path, pv.Message(), v)
+		return
+	case protoreflect.StringKind:
+		got, want = pv.String(), v.String()
+	case protoreflect.BoolKind:
+		got, want = pv.Bool(), v.Bool()
+	case protoreflect.Int32Kind, protoreflect.Int64Kind:
+		got, want = pv.Int(), v.Int()
+	case protoreflect.DoubleKind:
+		got, want = pv.Float(), v.Float()
+	default:
+		c.t.Errorf("%s has unsupported kind %s.", path, fd.Kind())
+		return
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		c.t.Errorf("%s (%s) diff: %s", path, fd.FullName(), diff)
+	}
+}
+
+// protoGoName returns the Go name of a field, e.g. PartOfSpeech for
+// part_of_speech.
+func protoGoName(name protoreflect.Name) string {
+	var b strings.Builder
+	for _, part := range strings.Split(string(name), "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+var protoScalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bool":   descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	"int32":  descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	"int64":  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"double": descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+}
+
+// parseProtoFile parses the subset of proto3 used by the schema: imports and
+// messages of scalar, message, repeated and map fields.
+func parseProtoFile(path string) (*descriptorpb.FileDescriptorProto, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	for _, line := range strings.Split(string(b), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		text.WriteString(line + "\n")
+	}
+	tokens := protoTokenRegexp.FindAllString(text.String(), -1)
+
+	fd := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String(filepath.Base(path)),
+		Syntax: proto.String("proto3"),
+	}
+	next := func() string {
+		if len(tokens) == 0 {
+			return ""
+		}
+		t := tokens[0]
+		tokens = tokens[1:]
+		return t
+	}
+	skipStatement := func() {
+		for t := next(); t != ";" && t != ""; t = next() {
+		}
+	}
+	// Types are qualified once the package is known.
+	var typeNames []*string
+
+	for len(tokens) > 0 {
+		switch t := next(); t {
+		case "syntax", "option":
+			skipStatement()
+		case "package":
+			fd.Package = proto.String(next())
+			skipStatement()
+		case "import":
+			fd.Dependency = append(fd.Dependency, strings.Trim(next(), `"`))
+			skipStatement()
+		case "message":
+			md := &descriptorpb.DescriptorProto{Name: proto.String(next())}
+			if next() != "{" {
+				return nil, fmt.Errorf("expected { after message %s", md.GetName())
+			}
+			for t := next(); t != "}"; t = next() {
+				if t == "" {
+					return nil, fmt.Errorf("unterminated message %s", md.GetName())
+				}
+				f := &descriptorpb.FieldDescriptorProto{Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()}
+				typ := t
+				if t == "repeated" {
+					f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+					typ = next()
+				}
+				if typ == "map" {
+					// map<K, V> is a repeated nested entry message.
+					next()
+					key := next()
+					next()
+					value := next()
+					next()
+					name := next()
+					entry := &descriptorpb.DescriptorProto{
+						Name:    proto.String(protoGoName(protoreflect.Name(name)) + "Entry"),
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					}
+					for i, kv := range []string{key, value} {
+						ef := &descriptorpb.FieldDescriptorProto{
+							Name:   proto.String([]string{"key", "value"}[i]),
+							Number: proto.Int32(int32(i + 1)),
+							Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						}
+						typeNames = append(typeNames, setProtoType(ef, kv)...)
+						entry.Field = append(entry.Field, ef)
+					}
+					md.NestedType = append(md.NestedType, entry)
+					f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+					f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+					f.TypeName = proto.String(md.GetName() + "." + entry.GetName())
+					typeNames = append(typeNames, f.TypeName)
+					f.Name = proto.String(name)
+				} else {
+					typeNames = append(typeNames, setProtoType(f, typ)...)
+					f.Name = proto.String(next())
+				}
+				if next() != "=" {
+					return nil, fmt.Errorf("expected = after %s.%s", md.GetName(), f.GetName())
+				}
+				num, err := strconv.Atoi(next())
+				if err != nil {
+					return nil, fmt.Errorf("invalid number for %s.%s: %s", md.GetName(), f.GetName(), err)
+				}
+				f.Number = proto.Int32(int32(num))
+				f.JsonName = proto.String(protoJSONName(f.GetName()))
+				skipStatement()
+				md.Field = append(md.Field, f)
+			}
+			fd.MessageType = append(fd.MessageType, md)
+		default:
+			return nil, fmt.Errorf("unexpected %q", t)
+		}
+	}
+
+	for _, name := range typeNames {
+		if !strings.Contains(*name, ".") || !strings.HasPrefix(*name, "google.") && !strings.HasPrefix(*name, "glossterm.") {
+			*name = fd.GetPackage() + "." + *name
+		}
+		*name = "." + *name
+	}
+	return fd, nil
+}
+
+var protoTokenRegexp = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_.]*|[0-9]+|"[^"]*"|[{}=;<>,]`)
+
+// setProtoType sets the type of a field, returning its type name to qualify
+// if it's a message.
+func setProtoType(f *descriptorpb.FieldDescriptorProto, typ string) []*string {
+	if t, ok := protoScalarTypes[typ]; ok {
+		f.Type = t.Enum()
+		return nil
+	}
+	f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	f.TypeName = proto.String(typ)
+	return []*string{f.TypeName}
+}
+
+// protoJSONName returns the JSON name of a field, e.g. partOfSpeech.
+func protoJSONName(name string) string {
+	goName := protoGoName(protoreflect.Name(name))
+	return strings.ToLower(goName[:1]) + goName[1:]
+}
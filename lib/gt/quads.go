@@ -0,0 +1,204 @@
+package gt
+
+import "github.com/vthommeret/glossterm/lib/tpl"
+
+// Only quads to and from Latin are created, since cognates are found through
+// Latin roots.
+const quadParentLang = "la"
+
+// WordQuads returns the quads for words, as written by cmd/gtquads: an edge
+// from each word in a source language to the Latin words in its etymology,
+// and descendant edges back from the Latin words.
+func WordQuads(words map[string]*Word) []QuadEdge {
+	edges, _ := wordQuads(func(fn func(*Word) error) error {
+		for _, w := range words {
+			if err := fn(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return edges
+}
+
+// ReadWordQuads returns the quads for the words in path. Word stores are read
+// twice instead of being loaded into memory.
+func ReadWordQuads(path string) ([]QuadEdge, error) {
+	return wordQuads(func(fn func(*Word) error) error {
+		return ForEachWord(path, fn)
+	})
+}
+
+func wordQuads(forEach func(fn func(*Word) error) error) ([]QuadEdge, error) {
+	// Find the roots of Latin words that are forms of other words, so words
+	// from e.g. "germana" are also connected to "germanus".
+	rootMap := map[string][]string{}
+	err := forEach(func(w *Word) error {
+		if l, ok := w.Languages[quadParentLang]; ok && l.Definitions != nil {
+			findRoots(rootMap, w.Name, l.AllDefinitions())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var edges []QuadEdge
+	err = forEach(func(w *Word) error {
+		for _, l := range w.Languages {
+			// Latin ancestors
+			if _, ok := SourceLangs[l.Code]; ok {
+				etymologyEdges(l.Etymology, func(predicate, toLang, toWord string) {
+					if toLang != quadParentLang {
+						return
+					}
+					// Not an ancestor, so there's no descendant quad back.
+					if predicate == NonCognatePredicate {
+						edges = append(edges, newQuadEdge(l.Code, w.Name, predicate, toLang, toWord))
+						return
+					}
+					edges = append(edges, ancestorQuads(rootMap, predicate, l.Code, w.Name, toLang, toWord)...)
+				})
+
+				// Latin descendants
+			} else if l.Code == quadParentLang {
+				descendantEdges(l, func(predicate, toLang, toWord string) {
+					if _, ok := SourceLangs[toLang]; ok {
+						edges = append(edges, newQuadEdge(l.Code, w.Name, predicate, toLang, toWord))
+					}
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort so quads are written in the same order each time.
+	return newQuadGraph(edges).Edges, nil
+}
+
+// etymologyEdges calls add with the quad from a word to each word its
+// etymology says it comes from or is related to.
+func etymologyEdges(e *Etymology, add func(predicate, toLang, toWord string)) {
+	if e == nil {
+		return
+	}
+	for _, c := range e.Cognates {
+		add("cognate", c.Lang, c.Word)
+	}
+	for _, s := range e.Suffixes {
+		add("suffix", s.Lang, s.Root)
+	}
+	for _, b := range e.Borrows {
+		add("borrowing-from", b.FromLang, b.FromWord)
+	}
+	for _, d := range e.Derived {
+		add("derived-from", d.FromLang, d.FromWord)
+	}
+	for _, i := range e.Inherited {
+		add("inherited-from", i.FromLang, i.FromWord)
+	}
+	for _, m := range e.Mentions {
+		add("mentions", m.Lang, m.Word)
+	}
+	for _, ln := range e.Links {
+		add("etyl", ln.Lang, ln.Word)
+	}
+	for _, ln := range e.Loans {
+		add(LoanPredicate(ln.Kind), ln.FromLang, ln.FromWord)
+	}
+	for _, a := range e.Affixes {
+		for _, p := range a.Parts {
+			add(a.Kind, a.PartLang(p), p.Word)
+		}
+	}
+	for _, r := range e.Roots {
+		for _, root := range r.Roots {
+			add(RootPredicate, r.RootLang, root)
+		}
+	}
+	// Doublets, back-formations and clippings are in the word's own
+	// language, so never lead to Latin.
+	for _, r := range e.Related {
+		for _, word := range r.Words {
+			add(RelatedPredicate(r.Kind), r.Lang, word)
+		}
+	}
+	for _, n := range e.NonCognates {
+		add(NonCognatePredicate, n.Lang, n.Word)
+	}
+}
+
+// descendantEdges calls add with the quad from a word to each descendant a
+// language lists. Links are mapped to "descendant", and Descendants to a
+// predicate for how they come from the word.
+func descendantEdges(l *Language, add func(predicate, toLang, toWord string)) {
+	for _, ln := range l.Links {
+		add(ChildPredicate, ln.Lang, ln.Word)
+	}
+	for _, d := range l.Descendants {
+		add(descendantPredicate(d), d.Lang, d.Word)
+	}
+}
+
+// LoanPredicate returns the quad from a word to the word it's a loan of,
+// e.g. "calque-from".
+func LoanPredicate(kind string) string {
+	return kind + "-from"
+}
+
+// RelatedPredicate returns the quad from a word to a word it's related to in
+// the same language, e.g. "clipping-from" or "doublet".
+func RelatedPredicate(kind string) string {
+	if kind == tpl.Doublet {
+		return kind
+	}
+	return kind + "-from"
+}
+
+// Quads from a word to a {{root}}, and to a word mentioned as not being a
+// cognate.
+const (
+	RootPredicate       = "root"
+	NonCognatePredicate = "noncognate"
+)
+
+func findRoots(rootMap map[string][]string, word string, allDefns [][]Definition) {
+	for _, defns := range allDefns {
+		for _, defn := range defns {
+			if defn.Root != nil {
+				rootMap[word] = append(rootMap[word], defn.Root.Name)
+			}
+		}
+	}
+}
+
+func ancestorQuads(rootMap map[string][]string, typ, lang, word, fromLang, fromWord string) []QuadEdge {
+	var edges []QuadEdge
+	if roots, ok := rootMap[fromWord]; ok {
+		for _, root := range roots {
+			edges = append(edges, newQuadEdge(lang, word, typ, fromLang, root))
+		}
+	}
+	edges = append(edges, newQuadEdge(lang, word, typ, fromLang, fromWord))
+	return append(edges, reverseQuads(edges)...)
+}
+
+func newQuadEdge(lang, word, typ, toLang, toWord string) QuadEdge {
+	return QuadEdge{
+		Source:    lang + "/" + word,
+		Predicate: typ,
+		Target:    toLang + "/" + toWord,
+	}
+}
+
+// Most Latin roots don't explicitly list every descendant, so create descendants explicitly.
+func reverseQuads(edges []QuadEdge) []QuadEdge {
+	var reversed []QuadEdge
+	for _, e := range edges {
+		reversed = append(reversed, QuadEdge{Source: e.Target, Predicate: ChildPredicate, Target: e.Source})
+	}
+	return reversed
+}
@@ -0,0 +1,247 @@
+package gt
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/vthommeret/glossterm/lib/lang"
+)
+
+// Number of words listed as example sources of each dangling reference.
+const maxRefExamples = 3
+
+// RefOptions configure FindDanglingRefs.
+type RefOptions struct {
+	// Suggest entries whose names only differ by diacritics or case.
+	Suggest bool
+}
+
+// RefReport lists the words referenced by links, mentions, descendants and
+// etymologies (borrowed, derived and inherited words, loans and affixes) that
+// don't have an entry, grouped by language and template.
+type RefReport struct {
+	Refs      int         `json:"refs"`
+	Dangling  int         `json:"dangling"`
+	Languages []*LangRefs `json:"languages"`
+}
+
+// LangRefs are the dangling references to words in a language.
+type LangRefs struct {
+	Lang      string          `json:"lang"`
+	Count     int             `json:"count"`
+	Templates []*TemplateRefs `json:"templates"`
+}
+
+// TemplateRefs are the dangling references to words in a language from one
+// kind of template, e.g. "mention".
+type TemplateRefs struct {
+	Template string         `json:"template"`
+	Count    int            `json:"count"`
+	Refs     []*DanglingRef `json:"refs"`
+}
+
+// DanglingRef is a referenced word without an entry, with the number of
+// references and some of the words referencing it.
+type DanglingRef struct {
+	Word        string   `json:"word"`
+	Count       int      `json:"count"`
+	Sources     []string `json:"sources"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// Templates reported by FindDanglingRefs. Loans and the parts of affixes are
+// reported by their kind, e.g. "calque" or "compound".
+const (
+	LinkRef       = "link"
+	MentionRef    = "mention"
+	DescendantRef = "descendant"
+	BorrowRef     = "borrow"
+	DerivedRef    = "derived"
+	InheritedRef  = "inherited"
+)
+
+// FindDanglingRefs returns the references in words to words without an
+// entry.
+func FindDanglingRefs(words map[string]*Word, opts RefOptions) *RefReport {
+	r, _ := danglingRefs(func(fn func(*Word) error) error {
+		for _, w := range words {
+			if err := fn(w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, opts)
+	return r
+}
+
+// ReadDanglingRefs returns the dangling references in the words (gob or
+// word store) at path, see FindDanglingRefs.
+func ReadDanglingRefs(path string, opts RefOptions) (*RefReport, error) {
+	return danglingRefs(func(fn func(*Word) error) error {
+		return ForEachWord(path, fn)
+	}, opts)
+}
+
+type refKey struct {
+	lang, template, word string
+}
+
+func danglingRefs(forEach func(fn func(*Word) error) error, opts RefOptions) (*RefReport, error) {
+	// Index every entry, and by normalized name for suggestions.
+	entries := map[string]bool{}
+	normalized := map[string][]string{}
+	err := forEach(func(w *Word) error {
+		for code := range w.Languages {
+			entries[code+"/"+w.Name] = true
+			if opts.Suggest {
+				k := code + "/" + Normalize(w.Name)
+				normalized[k] = append(normalized[k], w.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RefReport{Languages: []*LangRefs{}}
+	refs := map[refKey]*DanglingRef{}
+	err = forEach(func(w *Word) error {
+		for _, l := range w.Languages {
+			source := l.Code + "/" + w.Name
+			add := func(template, code, word string) {
+				if code == "" || word == "" || word == "-" {
+					return
+				}
+				r.Refs++
+				// Words in etymology-only languages like Late Latin have
+				// entries in their parent language.
+				code = lang.ToParent(code)
+				if entries[code+"/"+word] {
+					return
+				}
+				r.Dangling++
+				k := refKey{code, template, word}
+				ref, ok := refs[k]
+				if !ok {
+					ref = &DanglingRef{Word: word}
+					refs[k] = ref
+				}
+				ref.Count++
+				ref.Sources = append(ref.Sources, source)
+			}
+			for _, ln := range l.Links {
+				add(LinkRef, ln.Lang, ln.Word)
+			}
+			for _, d := range l.Descendants {
+				add(DescendantRef, d.Lang, d.Word)
+			}
+			if e := l.Etymology; e != nil {
+				for _, ln := range e.Links {
+					add(LinkRef, ln.Lang, ln.Word)
+				}
+				for _, m := range e.Mentions {
+					add(MentionRef, m.Lang, m.Word)
+				}
+				for _, b := range e.Borrows {
+					add(BorrowRef, b.FromLang, b.FromWord)
+				}
+				for _, d := range e.Derived {
+					add(DerivedRef, d.FromLang, d.FromWord)
+				}
+				for _, i := range e.Inherited {
+					add(InheritedRef, i.FromLang, i.FromWord)
+				}
+				for _, ln := range e.Loans {
+					add(ln.Kind, ln.FromLang, ln.FromWord)
+				}
+				for _, a := range e.Affixes {
+					for _, p := range a.Parts {
+						add(a.Kind, a.PartLang(p), p.Word)
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	langs := map[string]*LangRefs{}
+	templates := map[[2]string]*TemplateRefs{}
+	for k, ref := range refs {
+		ref.Sources = uniqueStrings(ref.Sources)
+		if len(ref.Sources) > maxRefExamples {
+			ref.Sources = ref.Sources[:maxRefExamples]
+		}
+		if opts.Suggest {
+			ref.Suggestions = append(ref.Suggestions, normalized[k.lang+"/"+Normalize(k.word)]...)
+			sort.Strings(ref.Suggestions)
+		}
+
+		l, ok := langs[k.lang]
+		if !ok {
+			l = &LangRefs{Lang: k.lang}
+			langs[k.lang] = l
+			r.Languages = append(r.Languages, l)
+		}
+		l.Count += ref.Count
+		t, ok := templates[[2]string{k.lang, k.template}]
+		if !ok {
+			t = &TemplateRefs{Template: k.template}
+			templates[[2]string{k.lang, k.template}] = t
+			l.Templates = append(l.Templates, t)
+		}
+		t.Count += ref.Count
+		t.Refs = append(t.Refs, ref)
+	}
+
+	// Most referenced first.
+	sort.Slice(r.Languages, func(i, j int) bool {
+		a, b := r.Languages[i], r.Languages[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Lang < b.Lang
+	})
+	for _, l := range r.Languages {
+		sort.Slice(l.Templates, func(i, j int) bool {
+			a, b := l.Templates[i], l.Templates[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Template < b.Template
+		})
+		for _, t := range l.Templates {
+			sort.Slice(t.Refs, func(i, j int) bool {
+				a, b := t.Refs[i], t.Refs[j]
+				if a.Count != b.Count {
+					return a.Count > b.Count
+				}
+				return a.Word < b.Word
+			})
+		}
+	}
+	return r, nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *RefReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// uniqueStrings returns the sorted unique strings of s.
+func uniqueStrings(s []string) []string {
+	sort.Strings(s)
+	var unique []string
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,113 @@
+package gt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+func TestFindDanglingRefs(t *testing.T) {
+	words := map[string]*Word{
+		"hermano": {Name: "hermano", Languages: map[string]*Language{"es": {
+			Code: "es",
+			Etymology: &Etymology{
+				Mentions: []tpl.Mention{{Lang: "la", Word: "germanus"}, {Lang: "la", Word: "germanūs"}},
+				Borrows:  []tpl.Borrow{{Lang: "es", FromLang: "LL", FromWord: "germānus"}},
+			},
+			Links: []tpl.Link{{Lang: "pt", Word: "-"}},
+		}}},
+		"hermana": {Name: "hermana", Languages: map[string]*Language{"es": {
+			Code:      "es",
+			Etymology: &Etymology{Mentions: []tpl.Mention{{Lang: "la", Word: "germānus"}}},
+		}}},
+		"hermandad": {Name: "hermandad", Languages: map[string]*Language{"es": {
+			Code: "es",
+			Etymology: &Etymology{
+				Derived:   []tpl.Derived{{Lang: "es", FromLang: "es", FromWord: "hermano"}},
+				Inherited: []tpl.Inherited{{Lang: "es", FromLang: "la", FromWord: "germanus"}},
+				Loans:     []tpl.Loan{{Lang: "es", FromLang: "fr", FromWord: "fraternité", Kind: tpl.Calque}},
+				Affixes:   []tpl.Affix{{Lang: "es", Parts: []tpl.AffixPart{{Word: "hermano"}, {Word: "-dad"}}, Kind: tpl.AffixKind}},
+			},
+		}}},
+		"germanus": {Name: "germanus", Languages: map[string]*Language{"la": {
+			Code: "la",
+			Descendants: []tpl.Descendant{
+				{Lang: "es", Word: "hermano"},
+				{Lang: "pt", Word: "irmão"},
+			},
+		}}},
+	}
+	got := FindDanglingRefs(words, RefOptions{Suggest: true})
+
+	// Late Latin words are looked up in Latin.
+	want := &RefReport{
+		Refs:     11,
+		Dangling: 6,
+		Languages: []*LangRefs{
+			{
+				Lang:  "la",
+				Count: 3,
+				Templates: []*TemplateRefs{
+					{
+						Template: MentionRef,
+						Count:    2,
+						Refs: []*DanglingRef{
+							{Word: "germanūs", Count: 1, Sources: []string{"es/hermano"}, Suggestions: []string{"germanus"}},
+							{Word: "germānus", Count: 1, Sources: []string{"es/hermana"}, Suggestions: []string{"germanus"}},
+						},
+					},
+					{
+						Template: BorrowRef,
+						Count:    1,
+						Refs: []*DanglingRef{
+							{Word: "germānus", Count: 1, Sources: []string{"es/hermano"}, Suggestions: []string{"germanus"}},
+						},
+					},
+				},
+			},
+			{
+				Lang:  "es",
+				Count: 1,
+				Templates: []*TemplateRefs{
+					{
+						Template: tpl.AffixKind,
+						Count:    1,
+						Refs:     []*DanglingRef{{Word: "-dad", Count: 1, Sources: []string{"es/hermandad"}}},
+					},
+				},
+			},
+			{
+				Lang:  "fr",
+				Count: 1,
+				Templates: []*TemplateRefs{
+					{
+						Template: tpl.Calque,
+						Count:    1,
+						Refs:     []*DanglingRef{{Word: "fraternité", Count: 1, Sources: []string{"es/hermandad"}}},
+					},
+				},
+			},
+			{
+				Lang:  "pt",
+				Count: 1,
+				Templates: []*TemplateRefs{
+					{
+						Template: DescendantRef,
+						Count:    1,
+						Refs:     []*DanglingRef{{Word: "irmão", Count: 1, Sources: []string{"la/germanus"}}},
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("gt.FindDanglingRefs(...) diff: %s", diff)
+	}
+
+	// Without suggestions.
+	got = FindDanglingRefs(words, RefOptions{})
+	if s := got.Languages[0].Templates[0].Refs[0].Suggestions; s != nil {
+		t.Errorf("gt.FindDanglingRefs(...) got suggestions %q, want none.", s)
+	}
+}
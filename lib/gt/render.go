@@ -0,0 +1,314 @@
+package gt
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/vthommeret/glossterm/lib/lang"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+// SpanType is the kind of content a span of definition text comes from.
+type SpanType string
+
+const (
+	TextSpan      SpanType = "text"
+	LinkSpan      SpanType = "link"
+	LabelSpan     SpanType = "label"
+	QualifierSpan SpanType = "qualifier"
+	GlossSpan     SpanType = "gloss"
+	NonGlossSpan  SpanType = "non-gloss"
+)
+
+// Span is a run of definition text of the same type and inline formatting.
+// Link spans also have the language and word they link to.
+type Span struct {
+	Type        SpanType `json:"type" firestore:"type"`
+	Text        string   `json:"text" firestore:"text"`
+	Lang        string   `json:"lang,omitempty" firestore:"lang,omitempty"`
+	Word        string   `json:"word,omitempty" firestore:"word,omitempty"`
+	Bold        bool     `json:"bold,omitempty" firestore:"bold,omitempty"`
+	Italic      bool     `json:"italic,omitempty" firestore:"italic,omitempty"`
+	Superscript bool     `json:"superscript,omitempty" firestore:"superscript,omitempty"`
+	Subscript   bool     `json:"subscript,omitempty" firestore:"subscript,omitempty"`
+}
+
+// canMerge reports whether o can be appended to s as a single span.
+func (s Span) canMerge(o Span) bool {
+	return s.Type == TextSpan && o.Type == TextSpan && s.Bold == o.Bold && s.Italic == o.Italic && s.Superscript == o.Superscript && s.Subscript == o.Subscript
+}
+
+// Links in definitions without a language section are to English entries.
+const definitionLang = "en"
+
+// wikiLink returns the word and language a wikilink points to, e.g.
+// [[libro#Spanish]]. It returns an empty word for links outside the main
+// namespace or within the page.
+func wikiLink(target string) (word, code string) {
+	if strings.Contains(target, ":") {
+		return "", ""
+	}
+	parts := strings.SplitN(target, "#", 2)
+	word = strings.TrimSpace(parts[0])
+	code = definitionLang
+	if len(parts) > 1 {
+		if l, ok := lang.CanonicalLangs[strings.TrimSpace(parts[1])]; ok {
+			code = l.Code
+		}
+	}
+	return word, code
+}
+
+// textRenderer renders wikitext to plain text and formatted spans. Entities
+// are decoded, and bold, italic, superscript and subscript formatting from
+// wiki markup or HTML tags is kept on the spans. Other HTML tags are dropped
+// but their contents kept.
+type textRenderer struct {
+	spans []Span
+
+	// Wiki markup toggles formatting while tags nest.
+	bold, italic    bool
+	boldTags        int
+	italicTags      int
+	superscriptTags int
+	subscriptTags   int
+
+	// Used when rendering items directly.
+	tag        htmlTag
+	link       *LinkBuffer
+	inLinkName bool
+}
+
+// text adds text with the current formatting, decoding HTML entities.
+func (r *textRenderer) text(s string) {
+	r.write(Span{Type: TextSpan, Text: s})
+}
+
+// typed adds text from a template of the given span type.
+func (r *textRenderer) typed(t SpanType, s string) {
+	r.write(Span{Type: t, Text: s})
+}
+
+// linkTo adds text linking to a word.
+func (r *textRenderer) linkTo(s, lang, word string) {
+	if word == "" {
+		r.text(s)
+		return
+	}
+	r.write(Span{Type: LinkSpan, Text: s, Lang: lang, Word: word})
+}
+
+// textWithRoot adds text containing a root word, linking the last occurrence
+// of the word.
+func (r *textRenderer) textWithRoot(s string, root *RootWord) {
+	i := -1
+	if root != nil && root.Name != "" {
+		i = strings.LastIndex(s, root.Name)
+	}
+	if i < 0 {
+		r.text(s)
+		return
+	}
+	r.text(s[:i])
+	r.linkTo(root.Name, root.Lang, root.Name)
+	r.text(s[i+len(root.Name):])
+}
+
+func (r *textRenderer) write(span Span) {
+	if span.Text == "" {
+		return
+	}
+	span.Text = html.UnescapeString(span.Text)
+	span.Bold = r.bold || r.boldTags > 0
+	span.Italic = r.italic || r.italicTags > 0
+	span.Superscript = r.superscriptTags > 0
+	span.Subscript = r.subscriptTags > 0
+	if n := len(r.spans); n > 0 && r.spans[n-1].canMerge(span) {
+		r.spans[n-1].Text += span.Text
+		return
+	}
+	r.spans = append(r.spans, span)
+}
+
+// markup toggles formatting for bold and italic wiki markup.
+func (r *textRenderer) markup(t itemType) {
+	switch t {
+	case itemStrongEmphasized:
+		r.bold = !r.bold
+		r.italic = !r.italic
+	case itemStrong:
+		r.bold = !r.bold
+	case itemEmphasized:
+		r.italic = !r.italic
+	}
+}
+
+// htmlTag applies formatting for an opening or closing HTML tag.
+func (r *textRenderer) htmlTag(t htmlTag) {
+	delta := 1
+	if t.closing {
+		delta = -1
+	}
+	switch t.name {
+	case "b", "strong":
+		r.boldTags = clampTags(r.boldTags + delta)
+	case "i", "em":
+		r.italicTags = clampTags(r.italicTags + delta)
+	case "sup":
+		r.superscriptTags = clampTags(r.superscriptTags + delta)
+	case "sub":
+		r.subscriptTags = clampTags(r.subscriptTags + delta)
+	case "br":
+		r.text(" ")
+	}
+}
+
+func clampTags(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// add renders an item, including links and HTML tags. It's used for text
+// that isn't otherwise parsed, such as citations.
+func (r *textRenderer) add(i item) {
+	switch i.typ {
+	case itemText:
+		if r.link != nil {
+			if r.inLinkName {
+				*r.link.Name += i.val
+			}
+			break
+		}
+		r.text(i.val)
+	case itemStrongEmphasized, itemStrong, itemEmphasized:
+		r.markup(i.typ)
+	case itemLeftLink:
+		r.link = &LinkBuffer{}
+		r.inLinkName = false
+	case itemLink:
+		if r.link != nil {
+			r.link.Link = i.val
+		}
+	case itemLinkDelim:
+		if r.link != nil {
+			var name string
+			r.link.Name = &name
+			r.inLinkName = true
+		}
+	case itemRightLink:
+		if r.link != nil {
+			word, code := wikiLink(r.link.Link)
+			if r.link.Name != nil {
+				r.linkTo(*r.link.Name, code, word)
+			} else if !strings.HasPrefix(r.link.Link, linkCategoryPrefix) {
+				r.linkTo(r.link.Link, code, word)
+			}
+			r.link = nil
+		}
+	case itemOpenTagLeft:
+		r.tag = htmlTag{}
+	case itemCloseTagLeft:
+		r.tag = htmlTag{closing: true}
+	case itemTagName:
+		r.tag.name = strings.ToLower(i.val)
+	case itemTagRight, itemCloseTagRight:
+		r.htmlTag(r.tag)
+	}
+}
+
+// template renders a template within text that isn't otherwise parsed.
+// Citations are summarized and other templates are kept as wikitext, with
+// parameter values rendered in both cases.
+func (r *textRenderer) template(t *tpl.Template) {
+	switch {
+	case t.Action == "l" || t.Action == "link":
+		link := t.ToLink()
+		r.linkTo(link.Text(), link.Lang, link.Word)
+	case t.Action == "m" || t.Action == "mention":
+		mention := t.ToMention()
+		r.mention(mention)
+	case strings.HasPrefix(t.Action, "cite-"):
+		cite := t.ToCite()
+		for _, p := range []*string{&cite.Author, &cite.Title, &cite.Publisher, &cite.Year} {
+			*p = renderText(*p)
+		}
+		r.text(cite.Text())
+	default:
+		r.text(renderParameters(t).String())
+	}
+}
+
+// renderParameters returns a copy of t with its parameter values rendered.
+func renderParameters(t *tpl.Template) *tpl.Template {
+	rendered := &tpl.Template{Action: t.Action}
+	for _, p := range t.Parameters {
+		rendered.Parameters = append(rendered.Parameters, renderText(p))
+	}
+	for _, p := range t.NamedParameters {
+		rendered.NamedParameters = append(rendered.NamedParameters, tpl.Parameter{Name: p.Name, Value: renderText(p.Value)})
+	}
+	return rendered
+}
+
+// renderText renders wikitext, such as a template parameter value, to plain
+// text.
+func renderText(s string) string {
+	if s == "" {
+		return s
+	}
+	r := &textRenderer{}
+	l := NewLexer(s)
+	defer l.Stop()
+	for {
+		i := l.NextItem()
+		if i.typ == itemEOF || i.typ == itemError {
+			break
+		}
+		r.add(i)
+	}
+	return r.String()
+}
+
+// mention adds a mentioned word and its gloss.
+func (r *textRenderer) mention(m tpl.Mention) {
+	r.linkTo(m.DisplayWord(), m.Lang, m.Word)
+	if m.Gloss != "" {
+		r.text(" ")
+		r.typed(GlossSpan, fmt.Sprintf("(%s)", m.Gloss))
+	}
+}
+
+// Spans returns the rendered spans without surrounding whitespace.
+func (r *textRenderer) Spans() []Span {
+	var spans []Span
+	for _, s := range r.spans {
+		if len(spans) == 0 {
+			s.Text = strings.TrimLeftFunc(s.Text, unicode.IsSpace)
+		}
+		if s.Text != "" {
+			spans = append(spans, s)
+		}
+	}
+	for len(spans) > 0 {
+		last := &spans[len(spans)-1]
+		last.Text = strings.TrimRightFunc(last.Text, unicode.IsSpace)
+		if last.Text != "" {
+			break
+		}
+		spans = spans[:len(spans)-1]
+	}
+	return spans
+}
+
+// String returns the rendered text without surrounding whitespace.
+func (r *textRenderer) String() string {
+	var b strings.Builder
+	for _, s := range r.spans {
+		b.WriteString(s.Text)
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,83 @@
+package gt
+
+import (
+	"math"
+
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+// CognateScorer scores paths to cognates by how reliable each quad is and how
+// many parents the path goes through.
+type CognateScorer struct {
+	// Weight of each predicate from 0 to 1. Predicates that aren't listed
+	// have a weight of 0.
+	Weights map[string]float64
+
+	// Multiplied once for each parent after the first, since second-degree
+	// parents are more likely to be unrelated.
+	Decay float64
+}
+
+// DefaultCognateScorer trusts inheritance and borrowing most, and mentions
+// least since etymologies often mention words for comparison.
+var DefaultCognateScorer = CognateScorer{
+	Weights: map[string]float64{
+		"inherited-from": 1,
+		"borrowing-from": 0.9,
+		"derived-from":   0.8,
+		"suffix":         0.7,
+		"cognate":        0.6,
+		"etyl":           0.5,
+		"mentions":       0.3,
+		ChildPredicate:   1,
+
+		LoanPredicate(tpl.LearnedBorrowing):      0.9,
+		LoanPredicate(tpl.OrthographicBorrowing): 0.9,
+		LoanPredicate(tpl.PhonoSemanticMatching): 0.6,
+		LoanPredicate(tpl.SemanticLoan):          0.5,
+		LoanPredicate(tpl.Calque):                0.5,
+		tpl.AffixKind:                            0.7,
+		tpl.Compound:                             0.7,
+		tpl.Confix:                               0.7,
+		tpl.Blend:                                0.6,
+		tpl.Univerbation:                         0.7,
+		RelatedPredicate(tpl.BackFormation):      0.8,
+		RelatedPredicate(tpl.Clipping):           0.8,
+
+		InheritedDescendantPredicate: 1,
+		BorrowedDescendantPredicate:  0.9,
+		DerivedDescendantPredicate:   0.8,
+	},
+	Decay: 0.8,
+}
+
+// Score returns the score of a path from 0 to 1, the product of the weights of
+// its quads and the decay for each extra parent.
+func (s CognateScorer) Score(path []CognateEdge) float64 {
+	score := 1.0
+	parents := 0
+	for _, e := range path {
+		score *= s.Weights[e.Predicate]
+		if !isChildPredicate(e.Predicate) {
+			parents++
+		}
+	}
+	if parents > 1 {
+		score *= math.Pow(s.Decay, float64(parents-1))
+	}
+	return score
+}
+
+// FilterCognates removes cognates scoring below min, and marks cognates
+// scoring below weak as Weak.
+func FilterCognates(cognates []*Cognate, min, weak float64) []*Cognate {
+	var filtered []*Cognate
+	for _, c := range cognates {
+		if c.Score < min {
+			continue
+		}
+		c.Weak = c.Score < weak
+		filtered = append(filtered, c)
+	}
+	return filtered
+}
@@ -0,0 +1,192 @@
+package gt
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/vthommeret/glossterm/lib/storage"
+)
+
+// Extension of word stores. Other paths are read as gob.
+const StoreExt = ".db"
+
+// IsStore returns whether path is a word store rather than a gob.
+func IsStore(path string) bool {
+	return filepath.Ext(path) == StoreExt
+}
+
+// WordStore is an on-disk store of words keyed by name, so a word can be read
+// without decoding every word. Words are stored as JSON.
+type WordStore struct {
+	s *storage.Store
+}
+
+// storedWord is a word as stored, with its digest, which isn't otherwise
+// encoded as JSON.
+type storedWord struct {
+	*Word
+	Digest string `json:"digest,omitempty"`
+}
+
+func encodeWord(w *Word) ([]byte, error) {
+	b, err := json.Marshal(storedWord{Word: w, Digest: w.Digest})
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode %q: %s", w.Name, err)
+	}
+	return b, nil
+}
+
+func decodeWord(name string, b []byte) (*Word, error) {
+	sw := storedWord{Word: &Word{}}
+	if err := json.Unmarshal(b, &sw); err != nil {
+		return nil, fmt.Errorf("unable to decode %q: %s", name, err)
+	}
+	sw.Word.Digest = sw.Digest
+	return sw.Word, nil
+}
+
+// OpenWordStore opens the word store at path for reading and writing,
+// creating it if it doesn't exist.
+func OpenWordStore(path string) (*WordStore, error) {
+	s, err := storage.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &WordStore{s: s}, nil
+}
+
+// OpenWordStoreReadOnly opens an existing word store at path for reading.
+func OpenWordStoreReadOnly(path string) (*WordStore, error) {
+	s, err := storage.OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	return &WordStore{s: s}, nil
+}
+
+// Close closes the store.
+func (ws *WordStore) Close() error {
+	return ws.s.Close()
+}
+
+// Get returns the word with name, or nil if it isn't in the store.
+func (ws *WordStore) Get(name string) (*Word, error) {
+	b, err := ws.s.Get(name)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return decodeWord(name, b)
+}
+
+// ForEach calls fn for each word in name order, stopping at the first error.
+func (ws *WordStore) ForEach(fn func(w *Word) error) error {
+	return ws.s.ForEach(func(name string, b []byte) error {
+		w, err := decodeWord(name, b)
+		if err != nil {
+			return err
+		}
+		return fn(w)
+	})
+}
+
+// Len returns the number of words.
+func (ws *WordStore) Len() (int, error) {
+	return ws.s.Len()
+}
+
+// Names returns the names of every word in the store, without decoding them.
+func (ws *WordStore) Names() ([]string, error) {
+	var names []string
+	err := ws.s.ForEach(func(name string, _ []byte) error {
+		names = append(names, name)
+		return nil
+	})
+	return names, err
+}
+
+// Put adds or replaces words in a single batch.
+func (ws *WordStore) Put(words []*Word) error {
+	b := ws.NewBatch(len(words))
+	for _, w := range words {
+		if err := b.Put(w); err != nil {
+			return err
+		}
+	}
+	return b.Flush()
+}
+
+// Words returns every word in the store.
+func (ws *WordStore) Words() (map[string]*Word, error) {
+	words := map[string]*Word{}
+	err := ws.ForEach(func(w *Word) error {
+		words[w.Name] = w
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// WordBatch buffers writes to a word store. Flush must be called to commit
+// the remaining writes.
+type WordBatch struct {
+	b *storage.Batch
+}
+
+// NewBatch returns a batch that commits every size writes, or
+// storage.DefaultBatchSize if size isn't positive.
+func (ws *WordStore) NewBatch(size int) *WordBatch {
+	return &WordBatch{b: ws.s.NewBatch(size)}
+}
+
+// Put adds or replaces a word.
+func (wb *WordBatch) Put(w *Word) error {
+	b, err := encodeWord(w)
+	if err != nil {
+		return err
+	}
+	return wb.b.Put(w.Name, b)
+}
+
+// Delete removes the word with name.
+func (wb *WordBatch) Delete(name string) error {
+	return wb.b.Delete(name)
+}
+
+// Flush commits the buffered writes.
+func (wb *WordBatch) Flush() error {
+	return wb.b.Flush()
+}
+
+// PutWords adds or replaces words in batches.
+func (ws *WordStore) PutWords(words map[string]*Word) error {
+	b := ws.NewBatch(storage.DefaultBatchSize)
+	for _, w := range words {
+		if err := b.Put(w); err != nil {
+			return err
+		}
+	}
+	return b.Flush()
+}
+
+// ConvertWords writes the words in a gob (or compressed gob) to a word store,
+// returning the number of words.
+func ConvertWords(gobPath, storePath string) (int, error) {
+	words, err := GetWords(gobPath)
+	if err != nil {
+		return 0, err
+	}
+	ws, err := OpenWordStore(storePath)
+	if err != nil {
+		return 0, err
+	}
+	if err := ws.PutWords(words); err != nil {
+		ws.Close()
+		return 0, err
+	}
+	return len(words), ws.Close()
+}
@@ -0,0 +1,106 @@
+package gt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/vthommeret/glossterm/lib/lang"
+)
+
+var storeTestPages = []Page{
+	{Title: "libros", Text: "==Spanish==\n\n===Noun===\n{{head|es|noun form}}\n# {{plural of|es|libro}}"},
+	{Title: "hermano", Text: "==Spanish==\n\n===Etymology===\nFrom {{inh|es|la|germānus}}.\n\n===Noun===\n# [[brother]]"},
+	{Title: "perro", Text: "==Spanish==\n\n===Noun===\n# [[dog]]\n\n====Descendants====\n* {{desc|en|perro}}"},
+}
+
+func TestWordStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatalf("Unable to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A few parsed words, plus one that was indexed.
+	words := map[string]*Word{}
+	for _, p := range storeTestPages {
+		w, err := ParseWord(p, lang.DefaultLangMap)
+		if err != nil {
+			t.Fatalf("gt.ParseWord(%q) got error: %s.", p.Title, err)
+		}
+		// Digests are kept so incremental parses can compare them.
+		if w.Digest, err = parseDigest(w); err != nil {
+			t.Fatalf("gt.parseDigest(%q) got error: %s.", p.Title, err)
+		}
+		words[w.Name] = &w
+	}
+	indexed := time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC)
+	words["casa"] = &Word{Name: "casa", Indexed: &indexed}
+
+	gobPath := filepath.Join(dir, "words.gob")
+	if err := WriteGob(gobPath, words, false, false); err != nil {
+		t.Fatalf("Unable to write %s: %s", gobPath, err)
+	}
+	storePath := filepath.Join(dir, "words.db")
+	n, err := ConvertWords(gobPath, storePath)
+	if err != nil {
+		t.Fatalf("gt.ConvertWords(%q, %q) got error: %s.", gobPath, storePath, err)
+	}
+	if n != len(words) {
+		t.Errorf("gt.ConvertWords(%q, %q) got %d words, want %d.", gobPath, storePath, n, len(words))
+	}
+
+	ignoreUnexported := cmpopts.IgnoreUnexported(Language{})
+
+	got, err := GetWords(storePath)
+	if err != nil {
+		t.Fatalf("gt.GetWords(%q) got error: %s.", storePath, err)
+	}
+	if diff := cmp.Diff(words, got, ignoreUnexported); diff != "" {
+		t.Errorf("gt.GetWords(%q) diff: %s", storePath, diff)
+	}
+
+	w, err := GetWord(storePath, "libros")
+	if err != nil {
+		t.Fatalf("gt.GetWord(%q, %q) got error: %s.", storePath, "libros", err)
+	}
+	if diff := cmp.Diff(words["libros"], w, ignoreUnexported); diff != "" {
+		t.Errorf("gt.GetWord(%q, %q) diff: %s", storePath, "libros", diff)
+	}
+	if w, err := GetWord(storePath, "missing"); err != nil || w != nil {
+		t.Errorf("gt.GetWord(%q, %q) got %v, %v, want nil.", storePath, "missing", w, err)
+	}
+
+	// Batched writes, iterating in name order.
+	ws, err := OpenWordStore(storePath)
+	if err != nil {
+		t.Fatalf("gt.OpenWordStore(%q) got error: %s.", storePath, err)
+	}
+	defer ws.Close()
+	b := ws.NewBatch(1)
+	if err := b.Delete("casa"); err != nil {
+		t.Fatalf("Unable to delete %q: %s", "casa", err)
+	}
+	if err := b.Put(&Word{Name: "agua"}); err != nil {
+		t.Fatalf("Unable to put %q: %s", "agua", err)
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatalf("Unable to flush: %s", err)
+	}
+	var names []string
+	err = ws.ForEach(func(w *Word) error {
+		names = append(names, w.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WordStore.ForEach() got error: %s.", err)
+	}
+	want := []string{"agua", "hermano", "libros", "perro"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("WordStore.ForEach() diff: %s", diff)
+	}
+}
@@ -0,0 +1,142 @@
+package gt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Layout of trees drawn by WriteSVG.
+const (
+	svgColumnWidth = 160
+	svgRowHeight   = 90
+	svgNodeWidth   = 140
+	svgNodeHeight  = 30
+	svgMargin      = 20
+)
+
+// svgPoint is the center of a node, in columns and rows.
+type svgPoint struct {
+	x float64
+	y int
+}
+
+// layoutTree places the leaves of a tree in consecutive columns, with each
+// word centered over its children. Rows go up for ancestors (dir -1) and down
+// for descendants (dir 1).
+func layoutTree(t *WordTree, row int, dir int, next *float64, points map[*WordTree]svgPoint) float64 {
+	x := *next
+	if len(t.Children) == 0 {
+		*next++
+	} else {
+		first := layoutTree(t.Children[0], row+dir, dir, next, points)
+		last := first
+		for _, c := range t.Children[1:] {
+			last = layoutTree(c, row+dir, dir, next, points)
+		}
+		x = (first + last) / 2
+	}
+	points[t] = svgPoint{x, row}
+	return x
+}
+
+func shiftTree(points map[*WordTree]svgPoint, dx float64) {
+	for n, p := range points {
+		p.x += dx
+		points[n] = p
+	}
+}
+
+// WriteSVG writes the trees as a standalone SVG, with ancestors above the word
+// and descendants below it.
+func (t EtymologyTree) WriteSVG(w io.Writer) error {
+	ancestors := map[*WordTree]svgPoint{}
+	descendants := map[*WordTree]svgPoint{}
+	var nextAncestor, nextDescendant float64
+	ax := layoutTree(t.Ancestors, 0, -1, &nextAncestor, ancestors)
+	dx := layoutTree(t.Descendants, 0, 1, &nextDescendant, descendants)
+
+	// Line up the word in both trees.
+	if ax < dx {
+		shiftTree(ancestors, dx-ax)
+	} else {
+		shiftTree(descendants, ax-dx)
+	}
+	minRow, maxRow := 0, 0
+	columns := 0.0
+	for _, points := range []map[*WordTree]svgPoint{ancestors, descendants} {
+		for _, p := range points {
+			if p.y < minRow {
+				minRow = p.y
+			}
+			if p.y > maxRow {
+				maxRow = p.y
+			}
+			if p.x+1 > columns {
+				columns = p.x + 1
+			}
+		}
+	}
+
+	center := func(p svgPoint) (float64, float64) {
+		return svgMargin + p.x*svgColumnWidth + svgNodeWidth/2, float64(svgMargin + (p.y-minRow)*svgRowHeight + svgNodeHeight/2)
+	}
+
+	var b strings.Builder
+	width := 2*svgMargin + int(math.Ceil(columns))*svgColumnWidth
+	height := 2*svgMargin + (maxRow-minRow)*svgRowHeight + svgNodeHeight
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	b.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto"><path d="M0,0 L10,5 L0,10 z"/></marker></defs>` + "\n")
+
+	// Edges point from the older word to the newer one, so down the page.
+	var writeEdges func(t *WordTree, points map[*WordTree]svgPoint)
+	writeEdges = func(t *WordTree, points map[*WordTree]svgPoint) {
+		for _, c := range t.Children {
+			top, bottom := points[c], points[t]
+			if top.y > bottom.y {
+				top, bottom = bottom, top
+			}
+			x1, y1 := center(top)
+			x2, y2 := center(bottom)
+			y1 += svgNodeHeight / 2
+			y2 -= svgNodeHeight / 2
+			fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#888" marker-end="url(#arrow)"/>`+"\n", x1, y1, x2, y2)
+			fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle" font-size="10" fill="#555">%s</text>`+"\n", (x1+x2)/2, (y1+y2)/2, svgEscape(strings.Join(c.Predicates, ", ")))
+			writeEdges(c, points)
+		}
+	}
+	writeEdges(t.Ancestors, ancestors)
+	writeEdges(t.Descendants, descendants)
+
+	writeNode := func(n *WordTree, p svgPoint, bold bool) {
+		x, y := center(p)
+		weight := "normal"
+		if bold {
+			weight = "bold"
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%d" height="%d" rx="4" fill="#fff" stroke="#333"/>`+"\n", x-svgNodeWidth/2, y-svgNodeHeight/2, svgNodeWidth, svgNodeHeight)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="middle" font-weight="%s">%s</text>`+"\n", x, y, weight, svgEscape(n.label()))
+	}
+	var writeNodes func(t *WordTree, points map[*WordTree]svgPoint)
+	writeNodes = func(t *WordTree, points map[*WordTree]svgPoint) {
+		for _, c := range t.Children {
+			writeNode(c, points[c], false)
+			writeNodes(c, points)
+		}
+	}
+	writeNodes(t.Ancestors, ancestors)
+	writeNodes(t.Descendants, descendants)
+	writeNode(t.Ancestors, ancestors[t.Ancestors], true)
+
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func svgEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package gt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WordTree is a word with the words it comes from (for ancestors) or the
+// words that come from it (for descendants).
+type WordTree struct {
+	Word       string      `json:"word"`                 // lang/word
+	Predicates []string    `json:"predicates,omitempty"` // quads between the word and the one above it
+	Children   []*WordTree `json:"children,omitempty"`
+}
+
+// EtymologyTree is the ancestors and descendants of a word, both rooted at the
+// word.
+type EtymologyTree struct {
+	Ancestors   *WordTree `json:"ancestors"`
+	Descendants *WordTree `json:"descendants"`
+}
+
+// GetEtymologyTree returns the ancestors of a word, following the same quads
+// as GetCognates, and its descendants, up to depth levels each. Words are
+// only included once in each tree, so cycles are cut off.
+func GetEtymologyTree(graph *Graph, lang string, word string, depth int) EtymologyTree {
+	w := fmt.Sprintf("%s/%s", lang, word)
+	return EtymologyTree{
+		Ancestors:   wordTree(graph, w, ParentPredicates, depth, map[string]bool{}),
+		Descendants: wordTree(graph, w, ChildPredicates, depth, map[string]bool{}),
+	}
+}
+
+func wordTree(graph *Graph, word string, predicates []string, depth int, seen map[string]bool) *WordTree {
+	t := &WordTree{Word: word}
+	seen[word] = true
+	if depth == 0 {
+		return t
+	}
+
+	// Group predicates by word so words connected by more than one quad are
+	// only included once.
+	neighbours := map[string][]string{}
+	var words []string
+	for _, e := range graph.Out(word, predicates...) {
+		if seen[e.To] {
+			continue
+		}
+		if _, ok := neighbours[e.To]; !ok {
+			words = append(words, e.To)
+		}
+		neighbours[e.To] = append(neighbours[e.To], e.Predicate)
+	}
+
+	for _, n := range words {
+		if seen[n] {
+			continue
+		}
+		child := wordTree(graph, n, predicates, depth-1, seen)
+		child.Predicates = neighbours[n]
+		t.Children = append(t.Children, child)
+	}
+	return t
+}
+
+// label returns e.g. "germanus (la)".
+func (t *WordTree) label() string {
+	n := NewQuadNode(t.Word)
+	if n.Lang == "" {
+		return n.Word
+	}
+	return fmt.Sprintf("%s (%s)", n.Word, n.Lang)
+}
+
+// WriteText writes the trees indented like tree(1).
+func (t EtymologyTree) WriteText(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("Ancestors:\n")
+	t.Ancestors.writeText(&b, "", "")
+	b.WriteString("Descendants:\n")
+	t.Descendants.writeText(&b, "", "")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (t *WordTree) writeText(b *strings.Builder, prefix, childPrefix string) {
+	b.WriteString(prefix + t.label())
+	if len(t.Predicates) > 0 {
+		fmt.Fprintf(b, " [%s]", strings.Join(t.Predicates, ", "))
+	}
+	b.WriteString("\n")
+	for i, c := range t.Children {
+		if i == len(t.Children)-1 {
+			c.writeText(b, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			c.writeText(b, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+// WriteDOT writes the trees as a Graphviz DOT graph, with ancestors above the
+// word and descendants below it.
+func (t EtymologyTree) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph etymology {\n")
+	fmt.Fprintf(&b, "  %s [label=%s, style=bold];\n", dotQuote(t.Ancestors.Word), dotQuote(t.Ancestors.label()))
+
+	// Edges always point from the older word to the newer one.
+	var writeTree func(t *WordTree, ancestors bool)
+	writeTree = func(t *WordTree, ancestors bool) {
+		for _, c := range t.Children {
+			fmt.Fprintf(&b, "  %s [label=%s];\n", dotQuote(c.Word), dotQuote(c.label()))
+			from, to := t.Word, c.Word
+			if ancestors {
+				from, to = to, from
+			}
+			fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(from), dotQuote(to), dotQuote(strings.Join(c.Predicates, ", ")))
+			writeTree(c, ancestors)
+		}
+	}
+	writeTree(t.Ancestors, true)
+	writeTree(t.Descendants, false)
+
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,136 @@
+package gt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// As written by cmd/gtquads, with a cycle back to es/hermano.
+const treeQuads = `"es/hermano" "inherited-from" "la/germanus" .
+"la/germanus" "descendant" "es/hermano" .
+"la/germanus" "derived-from" "la/germen" .
+"la/germanus" "mentions" "la/germen" .
+"la/germen" "descendant" "la/germanus" .
+"es/hermano" "descendant" "es/hermanito" .
+"es/hermanito" "suffix" "es/hermano" .
+"la/germen" "mentions" "es/hermano" .
+`
+
+func newTestGraph(t *testing.T, quads string) *Graph {
+	g, err := ReadGraphNquads(strings.NewReader(quads))
+	if err != nil {
+		t.Fatalf("Unable to read quads: %s", err)
+	}
+	return g
+}
+
+func TestGetEtymologyTree(t *testing.T) {
+	g := newTestGraph(t, treeQuads)
+	got := GetEtymologyTree(g, "es", "hermano", -1)
+
+	want := EtymologyTree{
+		Ancestors: &WordTree{
+			Word: "es/hermano",
+			Children: []*WordTree{
+				{
+					Word:       "la/germanus",
+					Predicates: []string{"inherited-from"},
+					Children: []*WordTree{
+						{Word: "la/germen", Predicates: []string{"derived-from", "mentions"}},
+					},
+				},
+			},
+		},
+		Descendants: &WordTree{
+			Word: "es/hermano",
+			Children: []*WordTree{
+				{Word: "es/hermanito", Predicates: []string{"descendant"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("gt.GetEtymologyTree(%q, %q) diff: %s", "es", "hermano", diff)
+	}
+
+	if got := GetEtymologyTree(g, "es", "hermano", 1); len(got.Ancestors.Children[0].Children) != 0 {
+		t.Errorf("gt.GetEtymologyTree(%q, %q, 1) got grandparents, want none.", "es", "hermano")
+	}
+}
+
+func TestEtymologyTreeText(t *testing.T) {
+	tree := GetEtymologyTree(newTestGraph(t, treeQuads), "es", "hermano", -1)
+
+	var b bytes.Buffer
+	if err := tree.WriteText(&b); err != nil {
+		t.Fatalf("EtymologyTree.WriteText() got error: %s.", err)
+	}
+	want := `Ancestors:
+hermano (es)
+└── germanus (la) [inherited-from]
+    └── germen (la) [derived-from, mentions]
+Descendants:
+hermano (es)
+└── hermanito (es) [descendant]
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("EtymologyTree.WriteText() diff: %s", diff)
+	}
+}
+
+func TestEtymologyTreeDOT(t *testing.T) {
+	tree := GetEtymologyTree(newTestGraph(t, treeQuads), "es", "hermano", -1)
+
+	var b bytes.Buffer
+	if err := tree.WriteDOT(&b); err != nil {
+		t.Fatalf("EtymologyTree.WriteDOT() got error: %s.", err)
+	}
+	want := `digraph etymology {
+  "es/hermano" [label="hermano (es)", style=bold];
+  "la/germanus" [label="germanus (la)"];
+  "la/germanus" -> "es/hermano" [label="inherited-from"];
+  "la/germen" [label="germen (la)"];
+  "la/germen" -> "la/germanus" [label="derived-from, mentions"];
+  "es/hermanito" [label="hermanito (es)"];
+  "es/hermano" -> "es/hermanito" [label="descendant"];
+}
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("EtymologyTree.WriteDOT() diff: %s", diff)
+	}
+}
+
+func TestEtymologyTreeSVG(t *testing.T) {
+	tree := GetEtymologyTree(newTestGraph(t, treeQuads), "es", "hermano", -1)
+
+	var b bytes.Buffer
+	if err := tree.WriteSVG(&b); err != nil {
+		t.Fatalf("EtymologyTree.WriteSVG() got error: %s.", err)
+	}
+
+	var svg struct {
+		Rects []struct{} `xml:"rect"`
+		Lines []struct{} `xml:"line"`
+		Texts []string   `xml:"text"`
+	}
+	if err := xml.Unmarshal(b.Bytes(), &svg); err != nil {
+		t.Fatalf("Unable to decode SVG: %s", err)
+	}
+	if len(svg.Rects) != 4 || len(svg.Lines) != 3 {
+		t.Errorf("EtymologyTree.WriteSVG() got %d words and %d edges, want 4 and 3.", len(svg.Rects), len(svg.Lines))
+	}
+	for _, label := range []string{"hermano (es)", "germen (la)", "derived-from, mentions"} {
+		found := false
+		for _, text := range svg.Texts {
+			if text == label {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("EtymologyTree.WriteSVG() missing %q.", label)
+		}
+	}
+}
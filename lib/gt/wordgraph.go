@@ -0,0 +1,357 @@
+package gt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/quad"
+	"github.com/cayleygraph/quad/nquads"
+)
+
+// Extension of nquads files, as written by cmd/gtquads.
+const NquadsExt = ".nq"
+
+// NodeID is the index of an interned lang/word in a Graph.
+type NodeID uint32
+
+// PredicateID is the index of an interned predicate in a Graph.
+type PredicateID uint16
+
+// Edge is a quad from or to a node in a Graph.
+type Edge struct {
+	Node      NodeID
+	Predicate PredicateID
+}
+
+// Graph is an in-memory etymology graph with the same quads as cmd/gtquads
+// writes. Words and predicates are interned and each node keeps its edges in
+// both directions, so it's much faster and smaller than a cayley memory
+// graph for the traversals used to find cognates. It isn't safe to add edges
+// while reading from other goroutines.
+type Graph struct {
+	nodes        []string
+	nodeIDs      map[string]NodeID
+	predicates   []string
+	predicateIDs map[string]PredicateID
+	out          [][]Edge
+	in           [][]Edge
+	edgeSet      map[edgeKey]bool
+	edges        int
+}
+
+// edgeKey identifies a quad, to ignore duplicates.
+type edgeKey struct {
+	from      NodeID
+	predicate PredicateID
+	to        NodeID
+}
+
+// NewGraph returns an empty graph.
+func NewGraph() *Graph {
+	return &Graph{
+		nodeIDs:      map[string]NodeID{},
+		predicateIDs: map[string]PredicateID{},
+		edgeSet:      map[edgeKey]bool{},
+	}
+}
+
+// NewGraphFromQuads returns the graph of edges.
+func NewGraphFromQuads(edges []QuadEdge) *Graph {
+	g := NewGraph()
+	for _, e := range edges {
+		g.AddEdge(e.Source, e.Predicate, e.Target)
+	}
+	return g
+}
+
+// BuildGraph returns the graph of words, without writing quads first.
+func BuildGraph(words map[string]*Word) *Graph {
+	return NewGraphFromQuads(WordQuads(words))
+}
+
+// ReadGraph returns the graph of the words (gob or word store) at path.
+func ReadGraph(path string) (*Graph, error) {
+	edges, err := ReadWordQuads(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewGraphFromQuads(edges), nil
+}
+
+// ReadGraphNquads returns the graph of the nquads in r, e.g. as written by
+// cmd/gtquads or WriteNquads.
+func ReadGraphNquads(r io.Reader) (*Graph, error) {
+	qg, err := ReadQuadGraph(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewGraphFromQuads(qg.Edges), nil
+}
+
+// LoadGraph returns the graph at path, reading nquads if path ends in
+// NquadsExt and words otherwise.
+func LoadGraph(path string) (*Graph, error) {
+	if filepath.Ext(path) != NquadsExt {
+		return ReadGraph(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadGraphNquads(bufio.NewReader(f))
+}
+
+// AddEdge adds a quad, interning the words and predicate. Duplicate quads are
+// ignored.
+func (g *Graph) AddEdge(from, predicate, to string) {
+	f, t := g.intern(from), g.intern(to)
+	p, ok := g.predicateIDs[predicate]
+	if !ok {
+		p = PredicateID(len(g.predicates))
+		g.predicates = append(g.predicates, predicate)
+		g.predicateIDs[predicate] = p
+	}
+	key := edgeKey{from: f, predicate: p, to: t}
+	if g.edgeSet[key] {
+		return
+	}
+	g.edgeSet[key] = true
+	g.out[f] = append(g.out[f], Edge{Node: t, Predicate: p})
+	g.in[t] = append(g.in[t], Edge{Node: f, Predicate: p})
+	g.edges++
+}
+
+func (g *Graph) intern(name string) NodeID {
+	if id, ok := g.nodeIDs[name]; ok {
+		return id
+	}
+	id := NodeID(len(g.nodes))
+	g.nodes = append(g.nodes, name)
+	g.nodeIDs[name] = id
+	g.out = append(g.out, nil)
+	g.in = append(g.in, nil)
+	return id
+}
+
+// Node returns the ID of a lang/word and whether it's in the graph.
+func (g *Graph) Node(name string) (NodeID, bool) {
+	id, ok := g.nodeIDs[name]
+	return id, ok
+}
+
+// Name returns the lang/word of a node.
+func (g *Graph) Name(id NodeID) string {
+	return g.nodes[id]
+}
+
+// Predicate returns the name of a predicate.
+func (g *Graph) Predicate(id PredicateID) string {
+	return g.predicates[id]
+}
+
+// NodeCount returns the number of words in the graph.
+func (g *Graph) NodeCount() int {
+	return len(g.nodes)
+}
+
+// EdgeCount returns the number of quads in the graph.
+func (g *Graph) EdgeCount() int {
+	return g.edges
+}
+
+// Out returns the quads from a word with any of the predicates, sorted by the
+// word they go to and then by the order of the predicates.
+func (g *Graph) Out(word string, predicates ...string) []CognateEdge {
+	id, ok := g.nodeIDs[word]
+	if !ok {
+		return nil
+	}
+	return g.edgesOf(g.out[id], predicates, func(e Edge) CognateEdge {
+		return CognateEdge{From: word, Predicate: g.predicates[e.Predicate], To: g.nodes[e.Node]}
+	})
+}
+
+// In returns the quads to a word with any of the predicates, sorted by the
+// word they come from and then by the order of the predicates.
+func (g *Graph) In(word string, predicates ...string) []CognateEdge {
+	id, ok := g.nodeIDs[word]
+	if !ok {
+		return nil
+	}
+	return g.edgesOf(g.in[id], predicates, func(e Edge) CognateEdge {
+		return CognateEdge{From: g.nodes[e.Node], Predicate: g.predicates[e.Predicate], To: word}
+	})
+}
+
+func (g *Graph) edgesOf(adj []Edge, predicates []string, edge func(Edge) CognateEdge) []CognateEdge {
+	// Rank predicates by their position so the order matches the order they
+	// were given in.
+	rank := make(map[PredicateID]int, len(predicates))
+	for i, p := range predicates {
+		if id, ok := g.predicateIDs[p]; ok {
+			if _, ok := rank[id]; !ok {
+				rank[id] = i
+			}
+		}
+	}
+	var edges []CognateEdge
+	var ranks []int
+	for _, e := range adj {
+		if r, ok := rank[e.Predicate]; ok {
+			edges = append(edges, edge(e))
+			ranks = append(ranks, r)
+		}
+	}
+	sort.Sort(edgesByWord{edges, ranks})
+	return edges
+}
+
+type edgesByWord struct {
+	edges []CognateEdge
+	ranks []int
+}
+
+func (s edgesByWord) Len() int { return len(s.edges) }
+
+func (s edgesByWord) Less(i, j int) bool {
+	a, b := s.edges[i], s.edges[j]
+	if a.To != b.To {
+		return a.To < b.To
+	}
+	if a.From != b.From {
+		return a.From < b.From
+	}
+	return s.ranks[i] < s.ranks[j]
+}
+
+func (s edgesByWord) Swap(i, j int) {
+	s.edges[i], s.edges[j] = s.edges[j], s.edges[i]
+	s.ranks[i], s.ranks[j] = s.ranks[j], s.ranks[i]
+}
+
+// Parents returns the quads from a word to the words it comes from.
+func (g *Graph) Parents(word string) []CognateEdge {
+	return g.Out(word, ParentPredicates...)
+}
+
+// Children returns the quads from a word to the words that come from it,
+// with one quad for each word.
+func (g *Graph) Children(word string) []CognateEdge {
+	var children []CognateEdge
+	for _, e := range g.Out(word, ChildPredicates...) {
+		// Quads to the same word are sorted most specific first.
+		if n := len(children); n > 0 && children[n-1].To == e.To {
+			continue
+		}
+		children = append(children, e)
+	}
+	return children
+}
+
+// Cousins returns every path from a word to the descendants of its ancestors
+// up to degree generations back, excluding words in the same language. Paths
+// are shortest first and don't go through the same ancestor twice, so cycles
+// are cut off.
+func (g *Graph) Cousins(lang, word string, degree int) map[string][][]CognateEdge {
+	prefix := lang + "/"
+	w := prefix + word
+
+	paths := map[string][][]CognateEdge{}
+	addChildren := func(path []CognateEdge) {
+		for _, e := range g.Children(path[len(path)-1].To) {
+			if strings.HasPrefix(e.To, prefix) {
+				continue
+			}
+			p := append(append([]CognateEdge(nil), path...), e)
+			paths[e.To] = append(paths[e.To], p)
+		}
+	}
+
+	// Find the children of each generation of ancestors in turn, so shorter
+	// paths come first.
+	var level [][]CognateEdge
+	for _, p := range g.Parents(w) {
+		level = append(level, []CognateEdge{p})
+	}
+	for i := 0; i < degree && len(level) > 0; i++ {
+		for _, path := range level {
+			addChildren(path)
+		}
+		if i == degree-1 {
+			break
+		}
+		var next [][]CognateEdge
+		for _, path := range level {
+			for _, e := range g.Parents(path[len(path)-1].To) {
+				if onPath(path, w, e.To) {
+					continue
+				}
+				next = append(next, append(append([]CognateEdge(nil), path...), e))
+			}
+		}
+		level = next
+	}
+	return paths
+}
+
+func onPath(path []CognateEdge, start, word string) bool {
+	if word == start {
+		return true
+	}
+	for _, e := range path {
+		if e.To == word {
+			return true
+		}
+	}
+	return false
+}
+
+// Quads returns every quad in the graph, sorted by source, target and
+// predicate.
+func (g *Graph) Quads() []QuadEdge {
+	edges := make([]QuadEdge, 0, g.edges)
+	for from, adj := range g.out {
+		for _, e := range adj {
+			edges = append(edges, QuadEdge{
+				Source:    g.nodes[from],
+				Predicate: g.predicates[e.Predicate],
+				Target:    g.nodes[e.Node],
+			})
+		}
+	}
+	return newQuadGraph(edges).Edges
+}
+
+// WriteNquads writes the graph as nquads, which can be read by
+// ReadGraphNquads or loaded into cayley.
+func (g *Graph) WriteNquads(w io.Writer) error {
+	nw := nquads.NewWriter(w)
+	for _, e := range g.Quads() {
+		if err := nw.WriteQuad(quad.Make(e.Source, e.Predicate, e.Target, nil)); err != nil {
+			return fmt.Errorf("unable to write quad: %s", err)
+		}
+	}
+	return nw.Close()
+}
+
+// Cayley returns the graph as a cayley memory graph, e.g. to run Gremlin
+// queries.
+func (g *Graph) Cayley() (*cayley.Handle, error) {
+	h, err := cayley.NewMemoryGraph()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range g.Quads() {
+		if err := h.AddQuad(quad.Make(e.Source, e.Predicate, e.Target, nil)); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
@@ -0,0 +1,201 @@
+package gt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cayleygraph/cayley"
+	"github.com/cayleygraph/quad"
+	"github.com/google/go-cmp/cmp"
+	"github.com/vthommeret/glossterm/lib/tpl"
+)
+
+func TestBuildGraph(t *testing.T) {
+	words := map[string]*Word{
+		"pelouse": {Name: "pelouse", Languages: map[string]*Language{"fr": {
+			Code: "fr",
+			Etymology: &Etymology{
+				Borrows:  []tpl.Borrow{{Lang: "fr", FromLang: "la", FromWord: "pilosa"}},
+				Mentions: []tpl.Mention{{Lang: "la", Word: "pilus"}, {Lang: "en", Word: "pile"}},
+			},
+		}}},
+		"pelo": {Name: "pelo", Languages: map[string]*Language{"es": {
+			Code:      "es",
+			Etymology: &Etymology{Inherited: []tpl.Inherited{{Lang: "es", FromLang: "la", FromWord: "pilus"}}},
+		}}},
+		// Forms of pilosus are connected to it as well.
+		"pilosa": {Name: "pilosa", Languages: map[string]*Language{"la": {
+			Code:        "la",
+			Definitions: &Definitions{Adjectives: []Definition{{Root: &RootWord{Name: "pilosus"}}}},
+		}}},
+		"pilosus": {Name: "pilosus", Languages: map[string]*Language{"la": {
+			Code:        "la",
+			Descendants: []tpl.Descendant{{Lang: "es", Word: "peloso", Borrowed: true}, {Lang: "it", Word: "peloso"}},
+		}}},
+		// Listed as a descendant and inherited from pilus.
+		"pilus": {Name: "pilus", Languages: map[string]*Language{"la": {
+			Code:        "la",
+			Descendants: []tpl.Descendant{{Lang: "es", Word: "pelo"}},
+		}}},
+	}
+	g := BuildGraph(words)
+
+	want := []QuadEdge{
+		{Source: "es/pelo", Target: "la/pilus", Predicate: "inherited-from"},
+		{Source: "fr/pelouse", Target: "la/pilosa", Predicate: "borrowing-from"},
+		{Source: "fr/pelouse", Target: "la/pilosus", Predicate: "borrowing-from"},
+		{Source: "fr/pelouse", Target: "la/pilus", Predicate: "mentions"},
+		{Source: "la/pilosa", Target: "fr/pelouse", Predicate: "descendant"},
+		{Source: "la/pilosus", Target: "es/peloso", Predicate: "borrowed-descendant"},
+		{Source: "la/pilosus", Target: "fr/pelouse", Predicate: "descendant"},
+		{Source: "la/pilus", Target: "es/pelo", Predicate: "descendant"},
+		{Source: "la/pilus", Target: "es/pelo", Predicate: "inherited-descendant"},
+		{Source: "la/pilus", Target: "fr/pelouse", Predicate: "descendant"},
+	}
+	if diff := cmp.Diff(want, g.Quads()); diff != "" {
+		t.Errorf("gt.BuildGraph(...) diff: %s", diff)
+	}
+	if got := g.NodeCount(); got != 6 {
+		t.Errorf("gt.BuildGraph(...) got %d nodes, want 6.", got)
+	}
+	if got := g.EdgeCount(); got != len(want) {
+		t.Errorf("gt.BuildGraph(...) got %d edges, want %d.", got, len(want))
+	}
+
+	// Only the most specific quad to each child is followed.
+	wantChildren := []CognateEdge{
+		{From: "la/pilus", Predicate: "inherited-descendant", To: "es/pelo"},
+		{From: "la/pilus", Predicate: "descendant", To: "fr/pelouse"},
+	}
+	if diff := cmp.Diff(wantChildren, g.Children("la/pilus")); diff != "" {
+		t.Errorf("Graph.Children(%q) diff: %s", "la/pilus", diff)
+	}
+}
+
+func TestBuildGraphRelations(t *testing.T) {
+	words := map[string]*Word{
+		"nacional": {Name: "nacional", Languages: map[string]*Language{"es": {
+			Code: "es",
+			Etymology: &Etymology{
+				Loans: []tpl.Loan{
+					{Lang: "es", FromLang: "la", FromWord: "nationalis", Kind: tpl.LearnedBorrowing},
+					{Lang: "es", FromLang: "fr", FromWord: "national", Kind: tpl.Calque},
+				},
+				Affixes: []tpl.Affix{{
+					Lang:  "es",
+					Parts: []tpl.AffixPart{{Word: "natio", Lang: "la"}, {Word: "-al"}},
+					Kind:  tpl.Compound,
+				}},
+				NonCognates: []tpl.NonCognate{{Lang: "la", Word: "navis"}},
+			},
+		}}},
+	}
+	g := BuildGraph(words)
+
+	// Only quads to Latin are kept, and non-cognates have no descendant
+	// quad back.
+	want := []QuadEdge{
+		{Source: "es/nacional", Target: "la/natio", Predicate: "compound"},
+		{Source: "es/nacional", Target: "la/nationalis", Predicate: "learned-borrowing-from"},
+		{Source: "es/nacional", Target: "la/navis", Predicate: "noncognate"},
+		{Source: "la/natio", Target: "es/nacional", Predicate: "descendant"},
+		{Source: "la/nationalis", Target: "es/nacional", Predicate: "descendant"},
+	}
+	if diff := cmp.Diff(want, g.Quads()); diff != "" {
+		t.Errorf("gt.BuildGraph(...) diff: %s", diff)
+	}
+}
+
+func TestGraphEdges(t *testing.T) {
+	g := newTestGraph(t, cognateQuads)
+
+	// Duplicates are ignored.
+	g.AddEdge("es/pelo", "inherited-from", "la/pilus")
+	if got := g.EdgeCount(); got != 10 {
+		t.Errorf("Graph.EdgeCount() got %d, want 10.", got)
+	}
+
+	parents := []CognateEdge{
+		{From: "fr/pelouse", Predicate: "borrowing-from", To: "la/pilosus"},
+		{From: "fr/pelouse", Predicate: "mentions", To: "la/pilus"},
+	}
+	if diff := cmp.Diff(parents, g.Parents("fr/pelouse")); diff != "" {
+		t.Errorf("Graph.Parents(%q) diff: %s", "fr/pelouse", diff)
+	}
+
+	children := []CognateEdge{
+		{From: "la/pilus", Predicate: "descendant", To: "es/pelo"},
+		{From: "la/pilus", Predicate: "descendant", To: "fr/pelouse"},
+		{From: "la/pilus", Predicate: "descendant", To: "la/pilosus"},
+	}
+	if diff := cmp.Diff(children, g.Children("la/pilus")); diff != "" {
+		t.Errorf("Graph.Children(%q) diff: %s", "la/pilus", diff)
+	}
+
+	in := []CognateEdge{
+		{From: "es/pelo", Predicate: "inherited-from", To: "la/pilus"},
+		{From: "fr/pelouse", Predicate: "mentions", To: "la/pilus"},
+		{From: "la/pilosus", Predicate: "derived-from", To: "la/pilus"},
+	}
+	if diff := cmp.Diff(in, g.In("la/pilus", ParentPredicates...)); diff != "" {
+		t.Errorf("Graph.In(%q) diff: %s", "la/pilus", diff)
+	}
+
+	if got := g.Parents("xx/missing"); got != nil {
+		t.Errorf("Graph.Parents(%q) got %v, want nil.", "xx/missing", got)
+	}
+}
+
+func TestGraphCousins(t *testing.T) {
+	g := newTestGraph(t, treeQuads)
+
+	// Only first cousins.
+	got := g.Cousins("es", "hermanito", 1)
+	if len(got) != 0 {
+		t.Errorf("Graph.Cousins(%q, %q, 1) got %v, want none.", "es", "hermanito", got)
+	}
+
+	// The cycle back through es/hermano is cut off.
+	got = g.Cousins("es", "hermanito", 4)
+	want := map[string][][]CognateEdge{
+		"la/germanus": {{
+			{From: "es/hermanito", Predicate: "suffix", To: "es/hermano"},
+			{From: "es/hermano", Predicate: "inherited-from", To: "la/germanus"},
+			{From: "la/germanus", Predicate: "derived-from", To: "la/germen"},
+			{From: "la/germen", Predicate: "descendant", To: "la/germanus"},
+		}, {
+			{From: "es/hermanito", Predicate: "suffix", To: "es/hermano"},
+			{From: "es/hermano", Predicate: "inherited-from", To: "la/germanus"},
+			{From: "la/germanus", Predicate: "mentions", To: "la/germen"},
+			{From: "la/germen", Predicate: "descendant", To: "la/germanus"},
+		}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Graph.Cousins(%q, %q, 4) diff: %s", "es", "hermanito", diff)
+	}
+}
+
+func TestGraphNquads(t *testing.T) {
+	g := newTestGraph(t, cognateQuads)
+
+	var b bytes.Buffer
+	if err := g.WriteNquads(&b); err != nil {
+		t.Fatalf("Graph.WriteNquads() got error: %s.", err)
+	}
+	got := newTestGraph(t, b.String())
+	if diff := cmp.Diff(g.Quads(), got.Quads()); diff != "" {
+		t.Errorf("Graph.WriteNquads() round trip diff: %s", diff)
+	}
+
+	h, err := g.Cayley()
+	if err != nil {
+		t.Fatalf("Graph.Cayley() got error: %s.", err)
+	}
+	var children []string
+	cayley.StartPath(h, quad.String("la/pilus")).Out(ChildPredicate).Iterate(nil).EachValue(nil, func(v quad.Value) {
+		children = append(children, quadString(v))
+	})
+	if len(children) != 3 {
+		t.Errorf("Graph.Cayley() got children %q for la/pilus, want 3.", children)
+	}
+}
@@ -1,47 +1,123 @@
 package gt
 
 import (
-	"compress/gzip"
-	"encoding/gob"
-	"fmt"
-	"io"
-	"log"
 	"os"
-	"path/filepath"
-	"strings"
+	"sort"
+	"sync"
 )
 
-// GetWords returns words either from path or compressed path.
+// Words are words that can be read and replaced by name, either a WordStore
+// or WordMap. Get may be called concurrently.
+type Words interface {
+	Names() ([]string, error)
+	Get(name string) (*Word, error)
+	Put(words []*Word) error
+}
+
+// WordMap is Words in memory, e.g. read with GetWords.
+type WordMap struct {
+	mu    sync.RWMutex
+	words map[string]*Word
+}
+
+// NewWordMap returns Words for a map, which is updated by Put.
+func NewWordMap(words map[string]*Word) *WordMap {
+	return &WordMap{words: words}
+}
+
+// Names returns the names of every word.
+func (m *WordMap) Names() ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var names []string
+	for name := range m.words {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Get returns the word with name, or nil if there isn't one.
+func (m *WordMap) Get(name string) (*Word, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.words[name], nil
+}
+
+// Put adds or replaces words.
+func (m *WordMap) Put(words []*Word) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, w := range words {
+		m.words[w.Name] = w
+	}
+	return nil
+}
+
+// GetWords returns words either from path or compressed path, or from a word
+// store if path ends in StoreExt.
 func GetWords(path string) (map[string]*Word, error) {
-	var f io.ReadCloser
-	if exists(path) {
-		wf, err := os.Open(path)
+	if IsStore(path) {
+		ws, err := OpenWordStoreReadOnly(path)
 		if err != nil {
 			return nil, err
 		}
-		f = wf
-	} else {
-		ext := filepath.Ext(path)
-		base := strings.TrimSuffix(path, ext)
-		compressed := fmt.Sprintf("%s.gob.gz", base)
-		log.Printf("Uncompressing %q.", compressed)
-		cf, err := os.Open(compressed)
+		defer ws.Close()
+		return ws.Words()
+	}
+
+	var words map[string]*Word
+	if err := ReadGob(path, &words); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// GetWord returns the word with name from path, or nil if there isn't one.
+// Only the word is decoded for word stores.
+func GetWord(path string, name string) (*Word, error) {
+	if IsStore(path) {
+		ws, err := OpenWordStoreReadOnly(path)
 		if err != nil {
 			return nil, err
 		}
-		gr, err := gzip.NewReader(cf)
+		defer ws.Close()
+		return ws.Get(name)
+	}
+	words, err := GetWords(path)
+	if err != nil {
+		return nil, err
+	}
+	return words[name], nil
+}
+
+// ForEachWord calls fn for each word in path in name order, stopping at the
+// first error. Word stores are read one word at a time, while gobs have to be
+// decoded completely first.
+func ForEachWord(path string, fn func(w *Word) error) error {
+	if IsStore(path) {
+		ws, err := OpenWordStoreReadOnly(path)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		f = gr
+		defer ws.Close()
+		return ws.ForEach(fn)
 	}
-	var words map[string]*Word
-	err := gob.NewDecoder(f).Decode(&words)
-	f.Close()
+	words, err := GetWords(path)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return words, nil
+	var names []string
+	for name := range words {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := fn(words[name]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func exists(file string) bool {
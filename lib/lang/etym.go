@@ -19,6 +19,15 @@ func ToParent(l string) string {
 	return l
 }
 
+// IsCode reports whether l is a known language or etymology language code.
+func IsCode(l string) bool {
+	if _, ok := Langs[l]; ok {
+		return true
+	}
+	_, ok := etymMap[l]
+	return ok
+}
+
 type Etym struct {
 	Canonical string
 	Parent    string
@@ -0,0 +1,109 @@
+package lang
+
+// Period is roughly when a language was spoken, so words can be checked
+// against the words they come from, e.g. Spanish can't be an ancestor of
+// Latin.
+type Period int
+
+const (
+	UnknownPeriod Period = iota
+	ProtoPeriod
+	AncientPeriod
+	MedievalPeriod
+	ModernPeriod
+)
+
+var periodNames = map[Period]string{
+	UnknownPeriod:  "unknown",
+	ProtoPeriod:    "proto",
+	AncientPeriod:  "ancient",
+	MedievalPeriod: "medieval",
+	ModernPeriod:   "modern",
+}
+
+func (p Period) String() string {
+	return periodNames[p]
+}
+
+// Periods of languages and varieties. Varieties of Latin are listed
+// separately since e.g. Medieval Latin is later than Latin.
+var periods = map[string]Period{
+	// Proto-languages
+	"ine-pro": ProtoPeriod,
+	"itc-pro": ProtoPeriod,
+	"gem-pro": ProtoPeriod,
+	"grk-pro": ProtoPeriod,
+	"cel-pro": ProtoPeriod,
+	"sla-pro": ProtoPeriod,
+
+	// Ancient
+	"la":      AncientPeriod,
+	"la-lat":  AncientPeriod,
+	"la-vul":  AncientPeriod,
+	"grc":     AncientPeriod,
+	"grc-koi": AncientPeriod,
+	"got":     AncientPeriod,
+	"frk":     AncientPeriod,
+	"osc":     AncientPeriod,
+	"xum":     AncientPeriod,
+	"ett":     AncientPeriod,
+	"xtg":     AncientPeriod,
+
+	// Medieval
+	"la-med":  MedievalPeriod,
+	"la-ecc":  MedievalPeriod,
+	"gkm":     MedievalPeriod,
+	"ang":     MedievalPeriod,
+	"enm":     MedievalPeriod,
+	"fro":     MedievalPeriod,
+	"frm":     MedievalPeriod,
+	"osp":     MedievalPeriod,
+	"roa-opt": MedievalPeriod,
+	"roa-oit": MedievalPeriod,
+	"pro":     MedievalPeriod,
+	"goh":     MedievalPeriod,
+	"gmh":     MedievalPeriod,
+	"non":     MedievalPeriod,
+	"odt":     MedievalPeriod,
+	"dum":     MedievalPeriod,
+	"xno":     MedievalPeriod,
+	"ota":     MedievalPeriod,
+
+	// Modern
+	"la-ren": ModernPeriod,
+	"la-new": ModernPeriod,
+	"en":     ModernPeriod,
+	"es":     ModernPeriod,
+	"fr":     ModernPeriod,
+	"pt":     ModernPeriod,
+	"it":     ModernPeriod,
+	"ca":     ModernPeriod,
+	"ro":     ModernPeriod,
+	"gl":     ModernPeriod,
+	"oc":     ModernPeriod,
+	"de":     ModernPeriod,
+	"nl":     ModernPeriod,
+	"el":     ModernPeriod,
+	"ar":     ModernPeriod,
+	"tr":     ModernPeriod,
+}
+
+func init() {
+	// Etymology-only codes like "LL" have the period of their first code.
+	for _, e := range etyms {
+		p, ok := periods[e.Codes[0]]
+		if !ok {
+			continue
+		}
+		for _, c := range e.Codes {
+			if _, ok := periods[c]; !ok {
+				periods[c] = p
+			}
+		}
+	}
+}
+
+// PeriodOf returns the period of a language code, or UnknownPeriod.
+func PeriodOf(code string) Period {
+	return periods[code]
+}
@@ -0,0 +1,23 @@
+package lang
+
+import "testing"
+
+func TestPeriodOf(t *testing.T) {
+	tests := []struct {
+		code string
+		want Period
+	}{
+		{"ine-pro", ProtoPeriod},
+		{"la", AncientPeriod},
+		{"LL", AncientPeriod},
+		{"ML.", MedievalPeriod},
+		{"fro", MedievalPeriod},
+		{"es", ModernPeriod},
+		{"xx", UnknownPeriod},
+	}
+	for _, tt := range tests {
+		if got := PeriodOf(tt.code); got != tt.want {
+			t.Errorf("PeriodOf(%q) = %s, want %s.", tt.code, got, tt.want)
+		}
+	}
+}
@@ -33,17 +33,16 @@ func (g *Graph) Query(q string) string {
 	ds := v.Out(quad.String("derived-from"))
 	is := v.Out(quad.String("inherited-from"))
 	ms := v.Out(quad.String("mentions"))
-	p := bs.Or(ds).Or(is).Or(ms).Out(quad.String("descendant"))
+	var children []interface{}
+	for _, c := range gt.ChildPredicates {
+		children = append(children, quad.String(c))
+	}
+	p := bs.Or(ds).Or(is).Or(ms).Out(children...)
 
-	rs, err := gt.QueryGraph(g.graph, p)
+	words, _, err := gt.QueryGraph(g.graph, p)
 	if err != nil {
 		log.Fatalf("Unable to execute query: %s", err)
 	}
 
-	var words []string
-	for _, r := range rs {
-		words = append(words, r.(string))
-	}
-
 	return strings.Join(words, ", ")
 }
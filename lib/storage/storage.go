@@ -0,0 +1,179 @@
+// Package storage is a keyed on-disk store, so commands can look up or
+// iterate over records without decoding everything into memory first.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Records are kept in a single bucket.
+var bucket = []byte("records")
+
+// Default number of writes committed per transaction.
+const DefaultBatchSize = 1000
+
+// Time to wait to acquire the file lock, e.g. if another command has the store
+// open for writing.
+const openTimeout = 5 * time.Second
+
+// ErrNotFound is returned by Get for keys that aren't in the store.
+var ErrNotFound = errors.New("not found")
+
+// Store is a bolt-backed store of records. Keys are sorted, so ForEach iterates
+// in key order.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the store at path for reading and writing, creating it if it
+// doesn't exist.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// OpenReadOnly opens an existing store at path for reading, which can be done
+// by more than one command at once.
+func OpenReadOnly(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: openTimeout, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucket) == nil {
+			return fmt.Errorf("%s isn't a store", path)
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the store.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Get returns the value for key, or ErrNotFound.
+func (s *Store) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucket).Get([]byte(key))
+		if v == nil {
+			return ErrNotFound
+		}
+		// Values are only valid during the transaction.
+		value = append([]byte(nil), v...)
+		return nil
+	})
+	return value, err
+}
+
+// ForEach calls fn for each record in key order, stopping at the first error.
+// The value is only valid until fn returns.
+func (s *Store) ForEach(fn func(key string, value []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
+
+// Len returns the number of records.
+func (s *Store) Len() (int, error) {
+	var n int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(bucket).Stats().KeyN
+		return nil
+	})
+	return n, err
+}
+
+// Batch buffers writes and commits them together, since committing each write
+// in its own transaction is slow.
+type Batch struct {
+	s       *Store
+	size    int
+	puts    map[string][]byte
+	deletes map[string]bool
+}
+
+// NewBatch returns a batch that commits every size writes. Flush must be
+// called to commit the remaining writes.
+func (s *Store) NewBatch(size int) *Batch {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	return &Batch{
+		s:       s,
+		size:    size,
+		puts:    map[string][]byte{},
+		deletes: map[string]bool{},
+	}
+}
+
+// Put sets the value for key.
+func (b *Batch) Put(key string, value []byte) error {
+	delete(b.deletes, key)
+	b.puts[key] = value
+	return b.maybeFlush()
+}
+
+// Delete removes key.
+func (b *Batch) Delete(key string) error {
+	delete(b.puts, key)
+	b.deletes[key] = true
+	return b.maybeFlush()
+}
+
+func (b *Batch) maybeFlush() error {
+	if len(b.puts)+len(b.deletes) < b.size {
+		return nil
+	}
+	return b.Flush()
+}
+
+// Flush commits the buffered writes.
+func (b *Batch) Flush() error {
+	if len(b.puts) == 0 && len(b.deletes) == 0 {
+		return nil
+	}
+	err := b.s.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucket)
+		for k, v := range b.puts {
+			if err := bk.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		for k := range b.deletes {
+			if err := bk.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.puts = map[string][]byte{}
+	b.deletes = map[string]bool{}
+	return nil
+}
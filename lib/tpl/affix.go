@@ -0,0 +1,85 @@
+package tpl
+
+import (
+	"strconv"
+
+	"github.com/vthommeret/glossterm/lib/lang"
+)
+
+// Kinds of words made of parts, from the template used.
+const (
+	AffixKind    = "affix"
+	Compound     = "compound"
+	Confix       = "confix"
+	Blend        = "blend"
+	Univerbation = "univerbation"
+)
+
+var affixKinds = map[string]string{
+	"af":           AffixKind,
+	"affix":        AffixKind,
+	"com":          Compound,
+	"compound":     Compound,
+	"confix":       Confix,
+	"blend":        Blend,
+	"univerbation": Univerbation,
+}
+
+// Affix is a word made of parts, e.g. {{af|es|nación|-al}}.
+//
+// https://en.wiktionary.org/wiki/Template:affix
+// https://en.wiktionary.org/wiki/Template:compound
+// https://en.wiktionary.org/wiki/Template:confix
+// https://en.wiktionary.org/wiki/Template:blend
+// https://en.wiktionary.org/wiki/Template:univerbation
+type Affix struct {
+	Lang  string      `json:"lang,omitempty" firestore:"lang,omitempty"`
+	Parts []AffixPart `json:"parts,omitempty" firestore:"parts,omitempty"`
+	Kind  string      `json:"kind,omitempty" firestore:"kind,omitempty"`
+}
+
+// AffixPart is a part of an Affix, with its numbered parameters, e.g. t2=.
+type AffixPart struct {
+	Word         string `json:"word,omitempty" firestore:"word,omitempty"`
+	Lang         string `json:"lang,omitempty" firestore:"lang,omitempty"` // set when not the word's language
+	Alt          string `json:"alt,omitempty" firestore:"alt,omitempty"`
+	Gloss        string `json:"gloss,omitempty" firestore:"gloss,omitempty"`
+	PartOfSpeech string `json:"partOfSpeech,omitempty" firestore:"partOfSpeech,omitempty"`
+}
+
+func (tpl *Template) ToAffix() Affix {
+	a := Affix{Kind: affixKinds[tpl.Action]}
+	if len(tpl.Parameters) == 0 {
+		return a
+	}
+	a.Lang = lang.ToParent(tpl.Parameters[0])
+	for i, word := range tpl.Parameters[1:] {
+		if word == "" {
+			continue
+		}
+		n := strconv.Itoa(i + 1)
+		p := AffixPart{
+			Word:         word,
+			Lang:         tpl.Value("lang" + n),
+			Alt:          tpl.Value("alt" + n),
+			Gloss:        tpl.Value("t"+n, "gloss"+n),
+			PartOfSpeech: tpl.Value("pos" + n),
+		}
+		if p.Lang != "" {
+			p.Lang = lang.ToParent(p.Lang)
+			p.Word = toEntryName(p.Lang, p.Word)
+		} else {
+			p.Word = toEntryName(a.Lang, p.Word)
+		}
+		a.Parts = append(a.Parts, p)
+	}
+	return a
+}
+
+// PartLang returns the language of a part.
+func (a *Affix) PartLang(p AffixPart) string {
+	if p.Lang != "" {
+		return p.Lang
+	}
+	return a.Lang
+}
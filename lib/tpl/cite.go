@@ -0,0 +1,30 @@
+package tpl
+
+import (
+	"reflect"
+	"strings"
+)
+
+// https://en.wiktionary.org/wiki/Template:cite-book
+type Cite struct {
+	Author    string `names:"author,last" json:"author,omitempty" firestore:"author,omitempty"`
+	Title     string `names:"title" json:"title,omitempty" firestore:"title,omitempty"`
+	Publisher string `names:"publisher,work,journal" json:"publisher,omitempty" firestore:"publisher,omitempty"`
+	Year      string `names:"year,date" json:"year,omitempty" firestore:"year,omitempty"`
+}
+
+func (tpl *Template) ToCite() Cite {
+	c := Cite{}
+	tpl.toConcrete(reflect.TypeOf(c), reflect.ValueOf(&c))
+	return c
+}
+
+func (c *Cite) Text() string {
+	var parts []string
+	for _, p := range []string{c.Author, c.Title, c.Publisher, c.Year} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -6,11 +6,50 @@ import "reflect"
 type Descendant struct {
 	Lang string `lang:"true" json:"lang,omitempty" firestore:"lang,omitempty"`
 	Word string `json:"word,omitempty" firestore:"word,omitempty"`
+	// Further terms, e.g. "bar" in {{desc|en|foo|bar}}.
+	Terms      []string `json:"terms,omitempty" firestore:"terms,omitempty"`
+	Alt        string   `names:"alt,alt1" json:"alt,omitempty" firestore:"alt,omitempty"`
+	Gloss      string   `names:"t,t1,gloss,gloss1" json:"gloss,omitempty" firestore:"gloss,omitempty"`
+	Translit   string   `names:"tr,tr1" json:"translit,omitempty" firestore:"translit,omitempty"`
+	Qualifiers []string `json:"qualifiers,omitempty" firestore:"qualifiers,omitempty"`
+
+	// How the descendant comes from the word, inherited unless set.
+	Borrowed         bool `names:"bor" json:"borrowed,omitempty" firestore:"borrowed,omitempty"`
+	LearnedBorrowing bool `names:"lbor" json:"learnedBorrowing,omitempty" firestore:"learnedBorrowing,omitempty"`
+	SemiLearned      bool `names:"slb" json:"semiLearned,omitempty" firestore:"semiLearned,omitempty"`
+	Calque           bool `names:"clq,cal,calq,calque" json:"calque,omitempty" firestore:"calque,omitempty"`
+	PartialCalque    bool `names:"pclq" json:"partialCalque,omitempty" firestore:"partialCalque,omitempty"`
+	SemanticLoan     bool `names:"sml" json:"semanticLoan,omitempty" firestore:"semanticLoan,omitempty"`
+	Transliterated   bool `names:"translit" json:"transliterated,omitempty" firestore:"transliterated,omitempty"`
+	Derived          bool `names:"der" json:"derived,omitempty" firestore:"derived,omitempty"`
+	Uncertain        bool `names:"unc" json:"uncertain,omitempty" firestore:"uncertain,omitempty"`
 }
 
 func (tpl *Template) ToDescendant() Descendant {
 	d := Descendant{}
-	tpl.toConcrete(reflect.TypeOf(d), reflect.ValueOf(&d))
+
+	// Only the first term fills Word, the rest are kept in Terms.
+	t := *tpl
+	if len(t.Parameters) > 2 {
+		t.Parameters = t.Parameters[:2]
+	}
+	t.toConcrete(reflect.TypeOf(d), reflect.ValueOf(&d))
 	d.Word = toEntryName(d.Lang, d.Word)
+
+	if len(tpl.Parameters) > 2 {
+		for _, term := range tpl.Parameters[2:] {
+			if term != "" {
+				d.Terms = append(d.Terms, toEntryName(d.Lang, term))
+			}
+		}
+	}
+	d.Qualifiers = tpl.Values("q", "q1", "qq", "qq1")
 	return d
 }
+
+// Borrowing returns whether the descendant was borrowed in any way, e.g. as
+// a learned borrowing or a calque.
+func (d *Descendant) Borrowing() bool {
+	return d.Borrowed || d.LearnedBorrowing || d.SemiLearned || d.Calque ||
+		d.PartialCalque || d.SemanticLoan || d.Transliterated
+}
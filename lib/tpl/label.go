@@ -735,6 +735,21 @@ func (tpl *Template) ToLabel() Label {
 }
 
 func (l *Label) Text() string {
+	return fmt.Sprintf("(%s)", strings.Join(expandLabels(l.labels()), ""))
+}
+
+// UnknownLabels returns labels without an entry, which are shown as is.
+func (l *Label) UnknownLabels() []string {
+	var unknown []string
+	for _, label := range l.labels() {
+		if _, ok := labelMap[label]; !ok {
+			unknown = append(unknown, label)
+		}
+	}
+	return unknown
+}
+
+func (l *Label) labels() []string {
 	var labels []string
 	if l.Label1 != "" {
 		labels = append(labels, l.Label1)
@@ -766,7 +781,7 @@ func (l *Label) Text() string {
 	if l.Label10 != "" {
 		labels = append(labels, l.Label10)
 	}
-	return fmt.Sprintf("(%s)", strings.Join(expandLabels(labels), ""))
+	return labels
 }
 
 func expandLabels(labels []string) []string {
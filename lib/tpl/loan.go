@@ -0,0 +1,53 @@
+package tpl
+
+import "reflect"
+
+// Kinds of loans, from the template used.
+const (
+	LearnedBorrowing      = "learned-borrowing"
+	OrthographicBorrowing = "orthographic-borrowing"
+	SemanticLoan          = "semantic-loan"
+	Calque                = "calque"
+	PhonoSemanticMatching = "phono-semantic-matching"
+)
+
+var loanKinds = map[string]string{
+	"lbor":                    LearnedBorrowing,
+	"learned borrowing":       LearnedBorrowing,
+	"obor":                    OrthographicBorrowing,
+	"orthographic borrowing":  OrthographicBorrowing,
+	"sl":                      SemanticLoan,
+	"semantic loan":           SemanticLoan,
+	"cal":                     Calque,
+	"calque":                  Calque,
+	"clq":                     Calque,
+	"psm":                     PhonoSemanticMatching,
+	"phono-semantic matching": PhonoSemanticMatching,
+}
+
+// Loan is a borrowing other than a plain {{bor}}, e.g. a learned borrowing
+// or a calque. Takes the same parameters as {{bor}}.
+//
+// https://en.wiktionary.org/wiki/Template:learned_borrowing
+// https://en.wiktionary.org/wiki/Template:orthographic_borrowing
+// https://en.wiktionary.org/wiki/Template:semantic_loan
+// https://en.wiktionary.org/wiki/Template:calque
+// https://en.wiktionary.org/wiki/Template:phono-semantic_matching
+type Loan struct {
+	Lang         string `lang:"true" json:"lang,omitempty" firestore:"lang,omitempty"`
+	FromLang     string `lang:"true" json:"fromLang,omitempty" firestore:"fromLang,omitempty"`
+	FromWord     string `json:"fromWord,omitempty" firestore:"fromWord,omitempty"`
+	Alt          string `names:"alt" json:"alt,omitempty" firestore:"alt,omitempty"`
+	Gloss        string `names:"t,gloss" json:"gloss,omitempty" firestore:"gloss,omitempty"`
+	PartOfSpeech string `names:"pos" json:"partOfSpeech,omitempty" firestore:"partOfSpeech,omitempty"`
+	Literal      string `names:"lit" json:"literal,omitempty" firestore:"literal,omitempty"`
+	Kind         string `json:"kind,omitempty" firestore:"kind,omitempty"`
+}
+
+func (tpl *Template) ToLoan() Loan {
+	l := Loan{}
+	tpl.toConcrete(reflect.TypeOf(l), reflect.ValueOf(&l))
+	l.FromWord = toEntryName(l.FromLang, l.FromWord)
+	l.Kind = loanKinds[tpl.Action]
+	return l
+}
@@ -22,13 +22,15 @@ func (tpl *Template) ToMention() Mention {
 	return m
 }
 
-func (m *Mention) Text() string {
-	var word string
+func (m *Mention) DisplayWord() string {
 	if m.Alt != "" {
-		word = m.Alt
-	} else {
-		word = m.Word
+		return m.Alt
 	}
+	return m.Word
+}
+
+func (m *Mention) Text() string {
+	word := m.DisplayWord()
 	var gloss string
 	if m.Gloss != "" {
 		gloss = fmt.Sprintf(" (%s)", m.Gloss)
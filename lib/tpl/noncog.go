@@ -0,0 +1,20 @@
+package tpl
+
+import "reflect"
+
+// A word mentioned as not being a cognate, e.g. a false friend.
+//
+// https://en.wiktionary.org/wiki/Template:noncognate
+type NonCognate struct {
+	Lang  string `lang:"true" json:"lang,omitempty" firestore:"lang,omitempty"`
+	Word  string `json:"word,omitempty" firestore:"word,omitempty"`
+	Alt   string `json:"alt,omitempty" firestore:"alt,omitempty"`
+	Gloss string `names:"t,gloss" json:"gloss,omitempty" firestore:"gloss,omitempty"`
+}
+
+func (tpl *Template) ToNonCognate() NonCognate {
+	n := NonCognate{}
+	tpl.toConcrete(reflect.TypeOf(n), reflect.ValueOf(&n))
+	n.Word = toEntryName(n.Lang, n.Word)
+	return n
+}
@@ -1,16 +1,39 @@
 package tpl
 
-import "reflect"
+import (
+	"reflect"
+
+	"github.com/vthommeret/glossterm/lib/lang"
+)
 
 // https://en.wiktionary.org/wiki/Template:prefix
 type Prefix struct {
-	Prefix string `json:"prefix,omitempty" firestore:"prefix,omitempty"`
-	Root   string `json:"root,omitempty" firestore:"root,omitempty"`
-	Lang   string `names:"lang" lang:"true" json:"lang,omitempty" firestore:"lang,omitempty"`
+	Prefix      string `json:"prefix,omitempty" firestore:"prefix,omitempty"`
+	Root        string `json:"root,omitempty" firestore:"root,omitempty"`
+	Lang        string `names:"lang" lang:"true" json:"lang,omitempty" firestore:"lang,omitempty"`
+	PrefixGloss string `names:"t1,gloss1" json:"prefixGloss,omitempty" firestore:"prefixGloss,omitempty"`
+	RootGloss   string `names:"t2,gloss2" json:"rootGloss,omitempty" firestore:"rootGloss,omitempty"`
 }
 
 func (tpl *Template) ToPrefix() Prefix {
 	p := Prefix{}
+
+	// Current templates give the language first rather than as lang=, e.g.
+	// {{prefix|es|des|hacer}}. Older ones, e.g. {{prefix|des|hacer|lang=es}},
+	// are left to toConcrete.
+	if tpl.Value("lang") == "" && len(tpl.Parameters) > 0 && lang.IsCode(tpl.Parameters[0]) {
+		p.Lang = lang.ToParent(tpl.Parameters[0])
+		if len(tpl.Parameters) > 1 {
+			p.Prefix = tpl.Parameters[1]
+		}
+		if len(tpl.Parameters) > 2 {
+			p.Root = tpl.Parameters[2]
+		}
+		p.PrefixGloss = tpl.Value("t1", "gloss1")
+		p.RootGloss = tpl.Value("t2", "gloss2")
+		return p
+	}
+
 	tpl.toConcrete(reflect.TypeOf(p), reflect.ValueOf(&p))
 	return p
 }
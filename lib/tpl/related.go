@@ -0,0 +1,67 @@
+package tpl
+
+import "github.com/vthommeret/glossterm/lib/lang"
+
+// Kinds of words related in the same language, from the template used.
+const (
+	Doublet       = "doublet"
+	BackFormation = "back-formation"
+	Clipping      = "clipping"
+)
+
+var relatedKinds = map[string]string{
+	"doublet":        Doublet,
+	"back-form":      BackFormation,
+	"back-formation": BackFormation,
+	"bf":             BackFormation,
+	"clipping":       Clipping,
+	"clip":           Clipping,
+}
+
+// Related is a word in the same language that a word is a doublet of, or a
+// back-formation or clipping of.
+//
+// https://en.wiktionary.org/wiki/Template:doublet
+// https://en.wiktionary.org/wiki/Template:back-formation
+// https://en.wiktionary.org/wiki/Template:clipping
+type Related struct {
+	Lang string `json:"lang,omitempty" firestore:"lang,omitempty"`
+	// Doublets can list several words, e.g. {{doublet|en|foo|bar}}.
+	Words []string `json:"words,omitempty" firestore:"words,omitempty"`
+	Alt   string   `json:"alt,omitempty" firestore:"alt,omitempty"`
+	Gloss string   `json:"gloss,omitempty" firestore:"gloss,omitempty"`
+	Kind  string   `json:"kind,omitempty" firestore:"kind,omitempty"`
+}
+
+func (tpl *Template) ToRelated() Related {
+	r := Related{Kind: relatedKinds[tpl.Action]}
+	if len(tpl.Parameters) == 0 {
+		return r
+	}
+	r.Lang = lang.ToParent(tpl.Parameters[0])
+	words := tpl.Parameters[1:]
+	if r.Kind != Doublet {
+		// Other positional parameters are the alt and gloss.
+		if len(words) > 1 {
+			r.Alt = words[1]
+		}
+		if len(words) > 2 {
+			r.Gloss = words[2]
+		}
+		if len(words) > 1 {
+			words = words[:1]
+		}
+	}
+	for _, w := range words {
+		if w != "" {
+			r.Words = append(r.Words, toEntryName(r.Lang, w))
+		}
+	}
+	if alt := tpl.Value("alt", "alt1"); alt != "" {
+		r.Alt = alt
+	}
+	if gloss := tpl.Value("t", "t1", "gloss", "gloss1"); gloss != "" {
+		r.Gloss = gloss
+	}
+	return r
+}
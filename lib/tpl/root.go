@@ -0,0 +1,28 @@
+package tpl
+
+import "github.com/vthommeret/glossterm/lib/lang"
+
+// https://en.wiktionary.org/wiki/Template:root
+type Root struct {
+	Lang     string   `json:"lang,omitempty" firestore:"lang,omitempty"`
+	RootLang string   `json:"rootLang,omitempty" firestore:"rootLang,omitempty"`
+	Roots    []string `json:"roots,omitempty" firestore:"roots,omitempty"`
+}
+
+func (tpl *Template) ToRoot() Root {
+	r := Root{}
+	if len(tpl.Parameters) > 0 {
+		r.Lang = lang.ToParent(tpl.Parameters[0])
+	}
+	if len(tpl.Parameters) > 1 {
+		r.RootLang = lang.ToParent(tpl.Parameters[1])
+	}
+	if len(tpl.Parameters) > 2 {
+		for _, root := range tpl.Parameters[2:] {
+			if root != "" {
+				r.Roots = append(r.Roots, toEntryName(r.RootLang, root))
+			}
+		}
+	}
+	return r
+}
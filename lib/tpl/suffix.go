@@ -1,16 +1,38 @@
 package tpl
 
-import "reflect"
+import (
+	"reflect"
+
+	"github.com/vthommeret/glossterm/lib/lang"
+)
 
 // https://en.wiktionary.org/wiki/Template:suffix
 type Suffix struct {
-	Lang   string `names:"lang" lang:"true" json:"lang,omitempty" firestore:"lang,omitempty"`
-	Root   string `json:"root,omitempty" firestore:"root,omitempty"`
-	Suffix string `json:"suffix,omitempty" firestore:"suffix,omitempty"`
+	Lang        string `names:"lang" lang:"true" json:"lang,omitempty" firestore:"lang,omitempty"`
+	Root        string `json:"root,omitempty" firestore:"root,omitempty"`
+	Suffix      string `json:"suffix,omitempty" firestore:"suffix,omitempty"`
+	RootGloss   string `names:"t1,gloss1" json:"rootGloss,omitempty" firestore:"rootGloss,omitempty"`
+	SuffixGloss string `names:"t2,gloss2" json:"suffixGloss,omitempty" firestore:"suffixGloss,omitempty"`
 }
 
 func (tpl *Template) ToSuffix() Suffix {
 	s := Suffix{}
+
+	// Older templates give the language as lang= rather than first, e.g.
+	// {{suffix|hacer|ción|lang=es}} rather than {{suffix|es|hacer|ción}}.
+	if tpl.Value("lang") != "" || len(tpl.Parameters) > 0 && !lang.IsCode(tpl.Parameters[0]) {
+		s.Lang = lang.ToParent(tpl.Value("lang"))
+		if len(tpl.Parameters) > 0 {
+			s.Root = tpl.Parameters[0]
+		}
+		if len(tpl.Parameters) > 1 {
+			s.Suffix = tpl.Parameters[1]
+		}
+		s.RootGloss = tpl.Value("t1", "gloss1")
+		s.SuffixGloss = tpl.Value("t2", "gloss2")
+		return s
+	}
+
 	tpl.toConcrete(reflect.TypeOf(s), reflect.ValueOf(&s))
 	return s
 }
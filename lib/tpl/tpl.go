@@ -18,6 +18,25 @@ type Parameter struct {
 	Value string
 }
 
+// String returns the template as wikitext.
+func (tpl *Template) String() string {
+	var b strings.Builder
+	b.WriteString("{{")
+	b.WriteString(tpl.Action)
+	for _, p := range tpl.Parameters {
+		b.WriteString("|")
+		b.WriteString(p)
+	}
+	for _, p := range tpl.NamedParameters {
+		b.WriteString("|")
+		b.WriteString(p.Name)
+		b.WriteString("=")
+		b.WriteString(p.Value)
+	}
+	b.WriteString("}}")
+	return b.String()
+}
+
 // toConcrete turns a generic template into a concrete struct.
 func (tpl *Template) toConcrete(t reflect.Type, v reflect.Value) {
 	v = v.Elem()
@@ -25,7 +44,7 @@ func (tpl *Template) toConcrete(t reflect.Type, v reflect.Value) {
 	// Set positional parameters.
 	n := len(tpl.Parameters)
 	for i := 0; i < t.NumField(); i++ {
-		if n > i {
+		if n > i && v.Field(i).Kind() == reflect.String {
 			str := tpl.Parameters[i]
 			if str != "" {
 				v.Field(i).SetString(str)
@@ -47,7 +66,12 @@ func (tpl *Template) toConcrete(t reflect.Type, v reflect.Value) {
 		vf := v.Field(i)
 		for _, p := range strings.Split(tf.Tag.Get("names"), ",") {
 			if val, ok := paramMap[p]; ok && val != "" {
-				vf.SetString(val)
+				switch vf.Kind() {
+				case reflect.String:
+					vf.SetString(val)
+				case reflect.Bool:
+					vf.SetBool(isTrue(val))
+				}
 			}
 		}
 		if tf.Tag.Get("lang") != "" {
@@ -66,3 +90,32 @@ func toEntryName(langName string, name string) string {
 	}
 	return l.MakeEntryName(name)
 }
+
+// Values returns the values of the named parameters, in the order given.
+func (tpl *Template) Values(names ...string) []string {
+	var values []string
+	for _, name := range names {
+		for _, p := range tpl.NamedParameters {
+			if p.Name == name && p.Value != "" {
+				values = append(values, p.Value)
+			}
+		}
+	}
+	return values
+}
+
+// Value returns the value of the first of the named parameters that is set.
+func (tpl *Template) Value(names ...string) string {
+	if values := tpl.Values(names...); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func isTrue(val string) bool {
+	switch strings.ToLower(val) {
+	case "1", "y", "yes", "t", "true", "on":
+		return true
+	}
+	return false
+}